@@ -49,9 +49,27 @@ type DeploymentConfig struct {
 		ResourceTimeoutMs int
 		BinaryMediaTypes  []*string
 	}
+	WebSocket struct {
+		Name                     string
+		Description              string
+		RouteSelectionExpression string
+		Routes                   []string
+		Stage                    string
+	}
+	RPC struct {
+		Services map[string]RPCService
+	}
 	BucketTriggers []BucketTrigger
 }
 
+// RPCService maps a logical RPC service name to a function, it's passed to the Lambda in the AEGIS_RPC_SERVICES
+// environment variable (the JSON is what the framework's RPCResolver expects)
+type RPCService struct {
+	FunctionName string `json:"functionName"`
+	Qualifier    string `json:"qualifier,omitempty"`
+	Region       string `json:"region,omitempty"`
+}
+
 // DeploymentStage defines an API Gateway stage and holds configuration options for it
 type DeploymentStage struct {
 	Name        string
@@ -70,6 +88,14 @@ type Task struct {
 	Name        string          `json:"-"` // Do not allow names to be set by JSON files (for now)
 }
 
+// EventRule defines options for a CloudWatch event rule that matches events by pattern (handled by EventBridgeRouter)
+type EventRule struct {
+	EventPattern CloudWatchRuleEventPattern `json:"eventPattern"`
+	Disabled     bool                       `json:"disabled"`
+	Description  string                     `json:"description"`
+	Name         string                     `json:"-"` // Set from the file name, just like tasks
+}
+
 // BucketTrigger defines options for S3 bucket notifications
 type BucketTrigger struct {
 	Bucket     *string
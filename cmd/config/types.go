@@ -3,10 +3,15 @@
 package config
 
 // CloudWatchRuleEventPattern defines an event pattern (ultimately sent as JSON)
+// Values are arrays of either exact values or content filters, ie. [{"prefix": "aws."}]
+// See: https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
 type CloudWatchRuleEventPattern struct {
-	Source     []string               `json:"source"`
-	DetailType []string               `json:"detail-type"`
-	Detail     map[string]interface{} `json:"detail"`
+	Source     []interface{}          `json:"source,omitempty"`
+	DetailType []interface{}          `json:"detail-type,omitempty"`
+	Detail     map[string]interface{} `json:"detail,omitempty"`
+	Account    []interface{}          `json:"account,omitempty"`
+	Region     []interface{}          `json:"region,omitempty"`
+	Resources  []interface{}          `json:"resources,omitempty"`
 }
 
 // S3BucketPolicy defines a generic bucket policy
@@ -135,6 +135,9 @@ func Deploy(cmd *cobra.Command, args []string) {
 		time.Sleep(4 * time.Second)
 	}
 
+	// Pass the RPC service names to the function so callers only need to reference logical names
+	injectRPCServices()
+
 	// Create (or update) the function
 	lambdaArn := createFunction(zipBytes)
 	// Set the lambdaArn on the deployer, many of its functions will need it
@@ -172,6 +175,9 @@ func Deploy(cmd *cobra.Command, args []string) {
 		fmt.Printf("%v %v %v\n", color.GreenString(key), "API URL:", color.GreenString(invokeURL))
 	}
 
+	// WebSocket API (only when configured)
+	deployer.DeployWebSocketAPI()
+
 	// Tasks - set CloudWatch scheduled events
 	lambdaArnStr := *lambdaArn
 	fmt.Printf("\n\nCloudWatch Event Rules (Tasks) for:\n%v\n\n", lambdaArnStr)
@@ -179,6 +185,9 @@ func Deploy(cmd *cobra.Command, args []string) {
 	// Tasks (CloudWatch event rules to trigger Lambda)
 	deployer.AddTasks()
 
+	// Event rules (CloudWatch event rules matching event patterns to trigger Lambda)
+	deployer.AddEventRules()
+
 	// Bucket notifications (to trigger Lambda)
 	deployer.AddS3BucketNotifications()
 
@@ -314,6 +323,24 @@ func getAWSSession() *session.Session {
 	return sess
 }
 
+// injectRPCServices will set the AEGIS_RPC_SERVICES environment variable with the configured RPC services,
+// which the framework's RPC client uses to resolve logical service names
+func injectRPCServices() {
+	if len(cfg.RPC.Services) == 0 {
+		return
+	}
+	services, err := json.Marshal(cfg.RPC.Services)
+	if err != nil {
+		fmt.Println("There was a problem with the RPC services configuration.")
+		fmt.Println(err)
+		return
+	}
+	if cfg.Lambda.EnvironmentVariables == nil {
+		cfg.Lambda.EnvironmentVariables = make(map[string]*string)
+	}
+	cfg.Lambda.EnvironmentVariables["AEGIS_RPC_SERVICES"] = aws.String(string(services))
+}
+
 // createFunction will create a Lambda function in AWS and return its ARN
 func createFunction(zipBytes []byte) *string {
 	svc := lambda.New(getAWSSession())
@@ -745,7 +772,7 @@ func addAPIPermission(apiID string, lambdaArn string) {
 	// http://stackoverflow.com/questions/39905255/how-can-i-grant-permission-to-api-gateway-to-invoke-lambda-functions-through-clo
 	// Glue together this weird SourceArn: arn:aws:execute-api:us-east-1:ACCOUNT_ID:API_ID/*/METHOD/ENDPOINT
 	// Not sure if some API call can get it?
-	accountID, region := getAccountInfoFromLambdaArn(lambdaArn)
+	accountID, region := deploy.GetAccountInfoFromLambdaArn(lambdaArn)
 
 	var buffer bytes.Buffer
 	buffer.WriteString("arn:aws:execute-api:")
@@ -809,20 +836,6 @@ func addBinaryMediaTypes(apiID string) {
 	}
 }
 
-// getAccountInfoFromArn will extract the account ID and region from a given ARN
-func getAccountInfoFromLambdaArn(lambdaArn string) (string, string) {
-	r, _ := regexp.Compile("arn:aws:lambda:(.+):([0-9]+):function")
-	matches := r.FindStringSubmatch(lambdaArn)
-	accountID := ""
-	region := ""
-	if len(matches) == 3 {
-		region = matches[1]
-		accountID = matches[2]
-	}
-
-	return accountID, region
-}
-
 // stripLamdaVersionFromArn will remove the :123 version number from a given Lambda ARN, which indicates to use the latest version when used in AWS
 func stripLamdaVersionFromArn(lambdaArn string) string {
 	// arn:aws:lambda:us-east-1:1234567890:function:aegis_example:1
@@ -17,6 +17,7 @@ type Deployer struct {
 	AWSSession *session.Session
 	LambdaArn  *string
 	TasksPath  string
+	EventsPath string
 }
 
 // NewDeployer takes a cfg argument to set the config needed for its various functions
@@ -27,6 +28,8 @@ func NewDeployer(cfg *config.DeploymentConfig, session *session.Session) *Deploy
 		// TasksPath defines the path to look for CloudWatch Event Rules ("tasks") defined in JSON files
 		// Not currently set via Cfg, but can be changed in this interface
 		TasksPath: "./tasks",
+		// EventsPath defines the path to look for CloudWatch Event Rules matching event patterns (for EventBridgeRouter)
+		EventsPath: "./events",
 	}
 	return &d
 }
@@ -62,3 +65,17 @@ func (d *Deployer) AddLambdaInvokePermission(sourceArn string, principal string,
 		}
 	}
 }
+
+// GetAccountInfoFromLambdaArn will extract the account ID and region from a given Lambda ARN
+func GetAccountInfoFromLambdaArn(lambdaArn string) (string, string) {
+	r, _ := regexp.Compile("arn:aws:lambda:(.+):([0-9]+):function")
+	matches := r.FindStringSubmatch(lambdaArn)
+	accountID := ""
+	region := ""
+	if len(matches) == 3 {
+		region = matches[1]
+		accountID = matches[2]
+	}
+
+	return accountID, region
+}
@@ -0,0 +1,112 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/fatih/color"
+	"github.com/tmaiaroto/aegis/cmd/config"
+)
+
+// AddEventRules will add CloudWatch event rules to trigger the Lambda with events matching patterns from an `events` directory.
+// Unlike tasks, the matched event itself is sent to the Lambda (to be handled by EventBridgeRouter).
+func (d *Deployer) AddEventRules() {
+	for _, rule := range d.getEventRules() {
+		ruleArn := d.addCloudWatchEventPatternRuleForLambda(rule, d.LambdaArn)
+		if ruleArn != "" {
+			// Statement IDs must be unique per rule
+			d.AddLambdaInvokePermission(ruleArn, "events.amazonaws.com", "aegis-event-rule-invoke-lambda-"+rule.Name)
+		}
+	}
+}
+
+// getEventRules will scan an `events` directory looking for JSON files with event patterns
+func (d *Deployer) getEventRules() []*config.EventRule {
+	var rules []*config.EventRule
+
+	// Don't proceed if the folder doesn't even exist.
+	if _, err := os.Stat(d.EventsPath); os.IsNotExist(err) {
+		return rules
+	}
+
+	files, err := ioutil.ReadDir(d.EventsPath)
+	if err != nil {
+		log.Printf("error opening events path: %s", err)
+		return rules
+	}
+	for _, file := range files {
+		if !file.Mode().IsRegular() || strings.ToLower(filepath.Ext(file.Name())) != ".json" {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(d.EventsPath, file.Name()))
+		if err != nil {
+			continue
+		}
+		var r config.EventRule
+		if err := json.Unmarshal(raw, &r); err != nil {
+			fmt.Printf("%v %v %v\n", color.YellowString("Warning:"), "Could not read event rule:", file.Name())
+			continue
+		}
+		// Named like tasks, but with an "event" prefix so the two won't clash: <function name>_event_<file name>
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		r.Name = strings.ToLower(d.Cfg.Lambda.FunctionName + "_event_" + name)
+		rules = append(rules, &r)
+	}
+
+	return rules
+}
+
+// addCloudWatchEventPatternRuleForLambda will add a CloudWatch Event Rule for triggering the Lambda with matching events
+func (d *Deployer) addCloudWatchEventPatternRuleForLambda(r *config.EventRule, lambdaArn *string) string {
+	state := "ENABLED"
+	if r.Disabled {
+		state = "DISABLED"
+	}
+
+	eventPattern, err := json.Marshal(r.EventPattern)
+	if err != nil {
+		fmt.Println("There was a problem with the event pattern for CloudWatch Event Rule:", r.Name)
+		fmt.Println(err)
+		return ""
+	}
+
+	svc := cloudwatchevents.New(d.AWSSession)
+	ruleOutput, err := svc.PutRule(&cloudwatchevents.PutRuleInput{
+		Description:  aws.String(r.Description),
+		Name:         aws.String(r.Name),
+		RoleArn:      aws.String(d.Cfg.Lambda.Role),
+		EventPattern: aws.String(string(eventPattern)),
+		State:        aws.String(state),
+	})
+	if err != nil {
+		fmt.Println("There was a problem creating a CloudWatch Event Rule.")
+		fmt.Println(err)
+		return ""
+	}
+
+	// Add the Lambda ARN as the target, without input so that the event is passed along
+	_, err = svc.PutTargets(&cloudwatchevents.PutTargetsInput{
+		Rule: aws.String(r.Name),
+		Targets: []*cloudwatchevents.Target{
+			&cloudwatchevents.Target{
+				Arn: lambdaArn,
+				Id:  aws.String(r.Name),
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println("There was an error setting the CloudWatch Event Rule Target (Lambda function).")
+		fmt.Println(err)
+	} else {
+		fmt.Printf("%v %v\n", "Added/updated Event Rule:", color.GreenString(r.Name))
+	}
+
+	return aws.StringValue(ruleOutput.RuleArn)
+}
@@ -0,0 +1,263 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/fatih/color"
+	swagger "github.com/tmaiaroto/aegis/apigateway"
+)
+
+// The vendored AWS SDK predates the apigatewayv2 package (used for WebSocket APIs), so the API Gateway V2
+// REST API is called directly with signed requests.
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/api-reference.html
+
+// webSocketAPI defines the parts of an API Gateway V2 API, integration, route and stage that deploy cares about
+type webSocketAPI struct {
+	APIID                    string `json:"apiId,omitempty"`
+	Name                     string `json:"name,omitempty"`
+	Description              string `json:"description,omitempty"`
+	ProtocolType             string `json:"protocolType,omitempty"`
+	RouteSelectionExpression string `json:"routeSelectionExpression,omitempty"`
+}
+
+type webSocketIntegration struct {
+	IntegrationID     string `json:"integrationId,omitempty"`
+	IntegrationType   string `json:"integrationType,omitempty"`
+	IntegrationMethod string `json:"integrationMethod,omitempty"`
+	IntegrationURI    string `json:"integrationUri,omitempty"`
+}
+
+type webSocketRoute struct {
+	RouteID  string `json:"routeId,omitempty"`
+	RouteKey string `json:"routeKey,omitempty"`
+	Target   string `json:"target,omitempty"`
+}
+
+type webSocketStage struct {
+	StageName   string `json:"stageName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// errAPIGatewayNotFound is returned for 404 responses from the API Gateway V2 API
+var errAPIGatewayNotFound = errors.New("not found")
+
+// DeployWebSocketAPI will create (or update) a WebSocket API with a route for each configured route key
+// that integrates with the Lambda, then deploy it to the configured stage.
+func (d *Deployer) DeployWebSocketAPI() {
+	if d.Cfg.WebSocket.Name == "" || len(d.Cfg.WebSocket.Routes) == 0 {
+		return
+	}
+	lambdaArn := aws.StringValue(d.LambdaArn)
+	accountID, region := GetAccountInfoFromLambdaArn(lambdaArn)
+
+	apiID, err := d.getOrCreateWebSocketAPI()
+	if err != nil {
+		fmt.Println("There was a problem creating the WebSocket API.")
+		fmt.Println(err)
+		return
+	}
+
+	integrationID, err := d.getOrCreateWebSocketIntegration(apiID, swagger.GetLambdaURI(lambdaArn))
+	if err != nil {
+		fmt.Println("There was a problem creating the WebSocket API Lambda integration.")
+		fmt.Println(err)
+		return
+	}
+
+	if err = d.addWebSocketRoutes(apiID, integrationID); err != nil {
+		fmt.Println("There was a problem adding routes to the WebSocket API.")
+		fmt.Println(err)
+		return
+	}
+
+	// Ensure the API can access the Lambda and the Lambda can send messages to the API's connections
+	apiArn := "arn:aws:execute-api:" + region + ":" + accountID + ":" + apiID + "/*"
+	d.AddLambdaInvokePermission(apiArn, "apigateway.amazonaws.com", "aegis-websocket-api-invoke-lambda")
+	d.addWebSocketConnectionsPolicy(apiArn)
+
+	stage := d.Cfg.WebSocket.Stage
+	if err = d.deployWebSocketAPI(apiID, stage); err != nil {
+		fmt.Println("There was a problem deploying the WebSocket API.")
+		fmt.Println(err)
+		return
+	}
+
+	webSocketURL := "wss://" + apiID + ".execute-api." + region + ".amazonaws.com/" + stage
+	fmt.Printf("%v %v %v\n", color.GreenString(stage), "WebSocket API URL:", color.GreenString(webSocketURL))
+}
+
+// addWebSocketConnectionsPolicy will add an inline policy to the Lambda's execution role that allows it to manage
+// connections (ie. send messages to clients) for the WebSocket API, and nothing else
+func (d *Deployer) addWebSocketConnectionsPolicy(apiArn string) {
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   "execute-api:ManageConnections",
+				"Resource": apiArn,
+			},
+		},
+	})
+
+	// The role name is the last part of its ARN, ie. arn:aws:iam::1234567890:role/aegis_lambda_role
+	roleName := d.Cfg.Lambda.Role[strings.LastIndex(d.Cfg.Lambda.Role, "/")+1:]
+	svc := iam.New(d.AWSSession)
+	_, err := svc.PutRolePolicy(&iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String("aegis_websocket_manage_connections_" + d.Cfg.WebSocket.Name),
+		PolicyDocument: aws.String(string(policy)),
+	})
+	if err != nil {
+		fmt.Println("There was a problem allowing the Lambda to manage WebSocket API connections.")
+		fmt.Println(err)
+	}
+}
+
+// getOrCreateWebSocketAPI returns the ID of the WebSocket API with the configured name, creating it if it doesn't exist.
+// Just like the REST API, names are not unique so the first match is used.
+func (d *Deployer) getOrCreateWebSocketAPI() (string, error) {
+	var apis struct {
+		Items []webSocketAPI `json:"items"`
+	}
+	if err := d.apiGatewayV2Request(http.MethodGet, "/v2/apis?maxResults=500", nil, &apis); err != nil {
+		return "", err
+	}
+	for _, api := range apis.Items {
+		if api.Name == d.Cfg.WebSocket.Name && api.ProtocolType == "WEBSOCKET" {
+			return api.APIID, nil
+		}
+	}
+
+	var api webSocketAPI
+	err := d.apiGatewayV2Request(http.MethodPost, "/v2/apis", webSocketAPI{
+		Name:                     d.Cfg.WebSocket.Name,
+		Description:              d.Cfg.WebSocket.Description,
+		ProtocolType:             "WEBSOCKET",
+		RouteSelectionExpression: d.Cfg.WebSocket.RouteSelectionExpression,
+	}, &api)
+	if err == nil {
+		fmt.Printf("%v %v\n", "Created WebSocket API:", color.GreenString(api.APIID))
+	}
+	return api.APIID, err
+}
+
+// getOrCreateWebSocketIntegration returns the ID of the Lambda proxy integration for the API, creating it if needed
+func (d *Deployer) getOrCreateWebSocketIntegration(apiID string, lambdaURI string) (string, error) {
+	var integrations struct {
+		Items []webSocketIntegration `json:"items"`
+	}
+	if err := d.apiGatewayV2Request(http.MethodGet, "/v2/apis/"+apiID+"/integrations?maxResults=500", nil, &integrations); err != nil {
+		return "", err
+	}
+	for _, integration := range integrations.Items {
+		if integration.IntegrationURI == lambdaURI {
+			return integration.IntegrationID, nil
+		}
+	}
+
+	var integration webSocketIntegration
+	err := d.apiGatewayV2Request(http.MethodPost, "/v2/apis/"+apiID+"/integrations", webSocketIntegration{
+		IntegrationType:   "AWS_PROXY",
+		IntegrationMethod: "POST",
+		IntegrationURI:    lambdaURI,
+	}, &integration)
+	return integration.IntegrationID, err
+}
+
+// addWebSocketRoutes will add any configured routes the API doesn't have yet. Existing routes are left alone.
+func (d *Deployer) addWebSocketRoutes(apiID string, integrationID string) error {
+	var routes struct {
+		Items []webSocketRoute `json:"items"`
+	}
+	if err := d.apiGatewayV2Request(http.MethodGet, "/v2/apis/"+apiID+"/routes?maxResults=500", nil, &routes); err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, route := range routes.Items {
+		existing[route.RouteKey] = true
+	}
+
+	for _, routeKey := range d.Cfg.WebSocket.Routes {
+		if existing[routeKey] {
+			continue
+		}
+		err := d.apiGatewayV2Request(http.MethodPost, "/v2/apis/"+apiID+"/routes", webSocketRoute{
+			RouteKey: routeKey,
+			Target:   "integrations/" + integrationID,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v %v\n", "Added WebSocket API route:", color.GreenString(routeKey))
+	}
+	return nil
+}
+
+// deployWebSocketAPI will create the stage if it doesn't exist and then deploy the API to it
+func (d *Deployer) deployWebSocketAPI(apiID string, stage string) error {
+	err := d.apiGatewayV2Request(http.MethodGet, "/v2/apis/"+apiID+"/stages/"+stage, nil, nil)
+	if err == errAPIGatewayNotFound {
+		err = d.apiGatewayV2Request(http.MethodPost, "/v2/apis/"+apiID+"/stages", webSocketStage{StageName: stage}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return d.apiGatewayV2Request(http.MethodPost, "/v2/apis/"+apiID+"/deployments", webSocketStage{
+		StageName:   stage,
+		Description: d.Cfg.WebSocket.Description,
+	}, nil)
+}
+
+// apiGatewayV2Request will make a signed request to the API Gateway V2 API and unmarshal the JSON response into out (if not nil)
+func (d *Deployer) apiGatewayV2Request(method string, path string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	region := aws.StringValue(d.AWSSession.Config.Region)
+	req, err := http.NewRequest(method, "https://apigateway."+region+".amazonaws.com"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signer := v4.NewSigner(d.AWSSession.Config.Credentials)
+	if _, err = signer.Sign(req, bytes.NewReader(body), "apigateway", region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errAPIGatewayNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return errors.New(resp.Status + " " + string(resBody))
+	}
+	if out != nil {
+		return json.Unmarshal(resBody, out)
+	}
+	return nil
+}
@@ -2,11 +2,25 @@ package cmd
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"github.com/tmaiaroto/aegis/cmd/config"
+	"github.com/tmaiaroto/aegis/cmd/deploy"
 )
 
+// testRPCServicesConfig is the rpc section of an aegis.yaml config file
+const testRPCServicesConfig = `
+rpc:
+  services:
+    geoip:
+      functionName: aegis_geoip
+      qualifier: live
+      region: us-west-2
+`
+
 func TestDeployCmd(t *testing.T) {
 	Convey("compress", t, func() {
 		Convey("Should compress a Lambda function zip file and return the file path", func() {
@@ -22,10 +36,10 @@ func TestDeployCmd(t *testing.T) {
 		})
 	})
 
-	Convey("getAccountInfoFromLambdaArn", t, func() {
+	Convey("GetAccountInfoFromLambdaArn", t, func() {
 		Convey("Should return account info from a given Lamba ARN", func() {
 			arn := "arn:aws:lambda:us-east-1:1234567890:function:aegis_example:1"
-			account, region := getAccountInfoFromLambdaArn(arn)
+			account, region := deploy.GetAccountInfoFromLambdaArn(arn)
 			So(account, ShouldEqual, "1234567890")
 			So(region, ShouldEqual, "us-east-1")
 		})
@@ -45,6 +59,29 @@ func TestDeployCmd(t *testing.T) {
 		})
 	})
 
+	Convey("injectRPCServices", t, func() {
+		Convey("Should set the RPC services environment variable", func() {
+			cfg.RPC.Services = map[string]config.RPCService{
+				"geoip": {FunctionName: "aegis_geoip", Qualifier: "live"},
+			}
+			defer func() {
+				cfg.RPC.Services = nil
+				delete(cfg.Lambda.EnvironmentVariables, "AEGIS_RPC_SERVICES")
+			}()
+			injectRPCServices()
+			So(*cfg.Lambda.EnvironmentVariables["AEGIS_RPC_SERVICES"], ShouldEqual, `{"geoip":{"functionName":"aegis_geoip","qualifier":"live"}}`)
+		})
+
+		Convey("Should read the services from the config file", func() {
+			v := viper.New()
+			v.SetConfigType("yaml")
+			So(v.ReadConfig(strings.NewReader(testRPCServicesConfig)), ShouldBeNil)
+			var c config.DeploymentConfig
+			So(v.Unmarshal(&c), ShouldBeNil)
+			So(c.RPC.Services["geoip"], ShouldResemble, config.RPCService{FunctionName: "aegis_geoip", Qualifier: "live", Region: "us-west-2"})
+		})
+	})
+
 	Convey("getExecPath", t, func() {
 		Convey("Should return a given executable file's path", func() {
 			So(getExecPath("go"), ShouldNotBeEmpty)
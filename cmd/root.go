@@ -131,6 +131,10 @@ func InitConfig() {
 		},
 	})
 
+	// Default WebSocket API config values (a WebSocket API is only deployed when a name and routes are configured)
+	viper.SetDefault("webSocket.routeSelectionExpression", "$request.body.action")
+	viper.SetDefault("webSocket.stage", "prod")
+
 	// By default do not keep the build files (clean up)
 	viper.SetDefault("app.keepBuildFiles", false)
 	// Just in case the temporary zip file that gets built creates a conflict, it can be adjusted. However, this is the default.
@@ -0,0 +1,173 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// rpcDescribeProcedure is the reserved procedure name the framework's RPCRouter returns its procedure catalogue for
+const rpcDescribeProcedure = "__describe"
+
+// rpcCmd groups commands for working with functions that listen for remote procedure calls
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Work with remote procedures",
+	Long:  `Commands for working with Lambda functions that handle remote procedure calls using the RPCRouter`,
+}
+
+// rpcDescribeCmd prints the procedures a function handles, along with their versions and schemas
+var rpcDescribeCmd = &cobra.Command{
+	Use:   "describe <function>",
+	Short: "Describe remote procedures",
+	Long:  `Prints the catalogue of procedures (with versions and JSON Schemas) a function's RPCRouter handles. The function can be a function name, ARN or a service name from the rpc config.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   RPCDescribe,
+}
+
+var rpcQualifier string
+var rpcJSON bool
+
+func init() {
+	rpcDescribeCmd.Flags().StringVarP(&rpcQualifier, "qualifier", "q", "", "Function version or alias to invoke")
+	rpcDescribeCmd.Flags().BoolVar(&rpcJSON, "json", false, "Print the catalogue as JSON")
+	rpcCmd.AddCommand(rpcDescribeCmd)
+	RootCmd.AddCommand(rpcCmd)
+}
+
+// RPCDescribe will invoke a function with the describe procedure and print its catalogue of procedures
+func RPCDescribe(cmd *cobra.Command, args []string) {
+	functionName, qualifier := rpcFunction(args[0])
+	payload, _ := json.Marshal(map[string]string{"_rpcName": rpcDescribeProcedure})
+	input := &lambda.InvokeInput{
+		FunctionName: aws.String(functionName),
+		Payload:      payload,
+	}
+	if qualifier != "" {
+		input.Qualifier = aws.String(qualifier)
+	}
+
+	svc := lambda.New(getAWSSession())
+	output, err := svc.Invoke(input)
+	if err == nil && output.FunctionError != nil {
+		err = fmt.Errorf("%s error: %s", aws.StringValue(output.FunctionError), output.Payload)
+	}
+	if err != nil {
+		fmt.Println("There was a problem invoking the function.")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	catalogue, err := formatRPCCatalogue(output.Payload, rpcJSON)
+	if err != nil {
+		fmt.Printf("%v %v\n", color.RedString("Error:"), err)
+		os.Exit(-1)
+	}
+	fmt.Print(catalogue)
+}
+
+// rpcFunction returns the function name and qualifier to invoke, looking up service names from the rpc config.
+// The --qualifier flag takes precedence over a configured service's qualifier.
+func rpcFunction(name string) (string, string) {
+	qualifier := rpcQualifier
+	if service, ok := cfg.RPC.Services[name]; ok && service.FunctionName != "" {
+		name = service.FunctionName
+		if qualifier == "" {
+			qualifier = service.Qualifier
+		}
+	}
+	return name, qualifier
+}
+
+// rpcCatalogue is the catalogue of procedures returned for the describe procedure
+type rpcCatalogue struct {
+	Procedures map[string]struct {
+		Version     string          `json:"version"`
+		Description string          `json:"description"`
+		Input       json.RawMessage `json:"input"`
+		Output      json.RawMessage `json:"output"`
+	} `json:"procedures"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"_rpcError"`
+}
+
+// formatRPCCatalogue returns the describe procedure's response for printing, either as indented JSON or as a
+// list of procedures (sorted by name) with their schemas
+func formatRPCCatalogue(payload []byte, asJSON bool) (string, error) {
+	var c rpcCatalogue
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return "", err
+	}
+	if c.Error != nil {
+		return "", fmt.Errorf("%s: %s", c.Error.Code, c.Error.Message)
+	}
+	if c.Procedures == nil {
+		return "", errors.New("the function did not return a procedure catalogue, is it using an RPCRouter?")
+	}
+
+	if asJSON {
+		var out bytes.Buffer
+		if err := json.Indent(&out, payload, "", "  "); err != nil {
+			return "", err
+		}
+		return out.String() + "\n", nil
+	}
+
+	names := make([]string, 0, len(c.Procedures))
+	for name := range c.Procedures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		p := c.Procedures[name]
+		out.WriteString(color.GreenString(name))
+		if p.Version != "" {
+			out.WriteString(" (v" + strings.TrimPrefix(p.Version, "v") + ")")
+		}
+		out.WriteString("\n")
+		if p.Description != "" {
+			out.WriteString("  " + p.Description + "\n")
+		}
+		for _, schema := range []struct {
+			label string
+			raw   json.RawMessage
+		}{{"input", p.Input}, {"output", p.Output}} {
+			if len(schema.raw) == 0 || string(schema.raw) == "null" {
+				continue
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, schema.raw, "    ", "  "); err != nil {
+				return "", err
+			}
+			out.WriteString("  " + schema.label + ":\n    " + indented.String() + "\n")
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tmaiaroto/aegis/cmd/config"
+)
+
+func TestRPCCmd(t *testing.T) {
+	color.NoColor = true
+
+	Convey("formatRPCCatalogue", t, func() {
+		payload := []byte(`{"procedures":{"ping":{},"lookup":{"version":"1.2.0","description":"Looks up a country","input":{"type":"object"}}}}`)
+
+		Convey("Should list the procedures sorted by name with their schemas", func() {
+			out, err := formatRPCCatalogue(payload, false)
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "lookup (v1.2.0)\n  Looks up a country\n  input:\n    {\n      \"type\": \"object\"\n    }\nping\n")
+		})
+
+		Convey("Should print the catalogue as JSON", func() {
+			out, err := formatRPCCatalogue(payload, true)
+			So(err, ShouldBeNil)
+			So(out, ShouldStartWith, "{\n  \"procedures\": {")
+		})
+
+		Convey("Should return an error for error envelopes and other responses", func() {
+			_, err := formatRPCCatalogue([]byte(`{"_rpcError":{"code":"Internal","message":"boom"}}`), false)
+			So(err.Error(), ShouldEqual, "Internal: boom")
+			_, err = formatRPCCatalogue([]byte(`{}`), false)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("rpcFunction", t, func() {
+		Convey("Should look up service names from the rpc config", func() {
+			cfg.RPC.Services = map[string]config.RPCService{
+				"geoip": {FunctionName: "aegis_geoip", Qualifier: "live"},
+			}
+			defer func() { cfg.RPC.Services = nil }()
+			name, qualifier := rpcFunction("geoip")
+			So(name, ShouldEqual, "aegis_geoip")
+			So(qualifier, ShouldEqual, "live")
+			name, qualifier = rpcFunction("aegis_other")
+			So(name, ShouldEqual, "aegis_other")
+			So(qualifier, ShouldEqual, "")
+		})
+	})
+}
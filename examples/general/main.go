@@ -21,6 +21,7 @@ import (
 	"errors"
 	"log"
 	"net/url"
+	"time"
 
 	aegis "github.com/tmaiaroto/aegis/framework"
 )
@@ -49,6 +50,13 @@ func main() {
 
 	// Handle with a URL reqeust path Router
 	router := aegis.NewRouter(fallThrough)
+	// Allow browsers on other origins to call the API, this also answers preflight requests
+	router.Use(aegis.CORSMiddleware(aegis.CORSConfig{
+		AllowOrigins: []string{"http://localhost:*", "https://*.example.com"},
+		MaxAge:       600,
+	}))
+	// Wrapping middleware runs around every handler, so it can see the response
+	router.Wrap(responseTime)
 
 	router.Handle("GET", "/", root)
 	router.Handle("GET", "/blah/:thing", somepath, fooMiddleware, barMiddleware)
@@ -65,7 +73,14 @@ func main() {
 
 	// Handle RPCs
 	rpcRouter := aegis.NewRPCRouter()
-	rpcRouter.Handle("procedure", handleProcedure)
+	// The optional contract is validated for each call and listed by `aegis rpc describe`
+	rpcRouter.Handle("procedure", handleProcedure, aegis.ProcedureContract{
+		Version:     "1.0.0",
+		Description: "Echoes the request back",
+		Input:       aegis.MustParseJSONSchema(`{"type": "object", "properties": {"message": {"type": "string"}}}`),
+	})
+	// The procedures can also be called over HTTP with JSON-RPC 2.0, POST {"jsonrpc": "2.0", "method": "procedure", "id": 1}
+	router.JSONRPC("/rpc", rpcRouter)
 
 	// Handle S3 objects
 	s3Router := aegis.NewS3ObjectRouterForBucket("aegis-incoming")
@@ -75,7 +90,7 @@ func main() {
 
 	// Handle Cognito Triggers
 	cognitoRouter := aegis.NewCognitoRouter()
-	cognitoRouter.Handle("PreSignUp_SignUp", handleCognitoPreSignUp)
+	cognitoRouter.PreSignUp(handleCognitoPreSignUp)
 
 	// Blocks. So this function would only be good for handling APIGatewayProxyRequest events
 	// router.Listen()
@@ -183,6 +198,16 @@ func barMiddleware(ctx context.Context, d *aegis.HandlerDependencies, req *aegis
 	return true
 }
 
+// HandlerMiddleware wraps the handler instead, so it can do something after the handler has run
+func responseTime(next aegis.RouteHandler) aegis.RouteHandler {
+	return func(ctx context.Context, d *aegis.HandlerDependencies, req *aegis.APIGatewayProxyRequest, res *aegis.APIGatewayProxyResponse, params url.Values) error {
+		start := time.Now()
+		err := next(ctx, d, req, res, params)
+		res.SetHeader("X-Response-Time", time.Since(start).String())
+		return err
+	}
+}
+
 // Redirect to AWS Cognito hosted signin page
 func redirectToCognitoSignin(ctx context.Context, d *aegis.HandlerDependencies, req *aegis.APIGatewayProxyRequest, res *aegis.APIGatewayProxyResponse, params url.Values) error {
 	log.Println("Redirect to login:", AegisApp.Services.Cognito.HostedLoginURL)
@@ -273,8 +298,8 @@ func handleS3Upload(ctx context.Context, d *aegis.HandlerDependencies, evt *aegi
 }
 
 // Example cognito handler
-func handleCognitoPreSignUp(ctx context.Context, d *aegis.HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+func handleCognitoPreSignUp(ctx context.Context, d *aegis.HandlerDependencies, evt *aegis.CognitoTriggerPreSignup) error {
 	log.Println("Handling Cognito Pre SignUp!")
-	log.Println(evt)
-	return evt, nil
+	log.Println(evt.UserName, evt.Request.UserAttributes)
+	return nil
 }
@@ -13,9 +13,29 @@ func main() {
 	// Handle an APIGatewayProxyRequest event with a URL reqeust path Router
 	router := aegis.NewRouter(fallThrough)
 	router.Handle("GET", "/", root, helloMiddleware)
+
+	// With AEGIS_RPC_TRANSPORT=local, remote procedure calls are handled in-process by RPCRouters bound to function names.
+	// This runs the example fully offline, with a local gateway and a stand-in for the aegis_geoip function.
+	if aegis.LocalRPCEnabled() {
+		aegis.BindLocalRPC("aegis_geoip", localGeoIP())
+		router.Gateway()
+		return
+	}
 	router.Listen()
 }
 
+// localGeoIP is a stand-in for the aegis_geoip function when running locally
+func localGeoIP() *aegis.RPCRouter {
+	rpcRouter := aegis.NewRPCRouter()
+	rpcRouter.Handle("lookup", func(ctx context.Context, d *aegis.HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"ipAddress": evt["ipAddress"],
+			"country":   "US",
+		}, nil
+	})
+	return rpcRouter
+}
+
 // fallThrough handles any path that couldn't be matched to another handler
 func fallThrough(ctx context.Context, d *aegis.HandlerDependencies, req *aegis.APIGatewayProxyRequest, res *aegis.APIGatewayProxyResponse, params url.Values) error {
 	res.StatusCode = 404
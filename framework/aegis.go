@@ -16,15 +16,11 @@ package framework
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/aws/session"
-	lambdaSDK "github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/sirupsen/logrus"
 )
@@ -44,6 +40,18 @@ type (
 	// S3Event alias
 	S3Event events.S3Event
 
+	// SNSEvent alias
+	SNSEvent events.SNSEvent
+
+	// SNSEventRecord alias for a single SNSEvent record, additional functionality added by sns.go
+	SNSEventRecord events.SNSEventRecord
+
+	// KinesisEvent alias
+	KinesisEvent events.KinesisEvent
+
+	// KinesisEventRecord alias for a single KinesisEvent record, additional functionality added by kinesis.go
+	KinesisEventRecord events.KinesisEventRecord
+
 	// CognitoEvent alias (NOT a Cognito Trigger event, this is for sync)
 	CognitoEvent events.CognitoEvent
 
@@ -76,6 +84,8 @@ type Aegis struct {
 // Services defines core framework services such as auth
 type Services struct {
 	Cognito        *CognitoAppClient
+	WebSocket      *WebSocketClient
+	RPC            *RPCClient
 	configurations map[string]func(context.Context, map[string]interface{}) interface{}
 }
 
@@ -112,8 +122,11 @@ func (a *Aegis) ConfigureService(name string, cfg func(context.Context, map[stri
 
 // aegisHandler configures services and determines how to handle the Lambda event
 func (a *Aegis) aegisHandler(ctx context.Context, evt map[string]interface{}) (interface{}, error) {
-	if a.TraceContext == nil {
-		a.TraceContext = ctx
+	// The trace context is the context of the current invocation, it holds the trace segment and the invocation deadline
+	a.TraceContext = ctx
+	// Remote procedure calls made while handling one keep the caller's correlation ID
+	if id := rpcTraceCarrierFrom(ctx, evt)[CorrelationIDHeader]; id != "" {
+		a.TraceContext = WithCorrelationID(ctx, id)
 	}
 
 	// Filters to run before anything is handled, even before services are configured.
@@ -151,6 +164,21 @@ func (a *Aegis) aegisHandler(ctx context.Context, evt map[string]interface{}) (i
 		}
 	}
 
+	// If a "websocket" configuration function was provided and the WebSocket client has not been configured already.
+	// Otherwise WebSocketRouter will configure one for the API that each request came through.
+	if sCfg, ok := a.Services.configurations["websocket"]; ok && a.Services.WebSocket == nil {
+		wsCfg := sCfg(ctx, evt).(*WebSocketClientConfig)
+		svc, err := NewWebSocketClient(wsCfg)
+		if err != nil {
+			log.Println("WebSocket client could not be configured")
+			log.Println(err)
+		}
+		a.Services.WebSocket = svc
+	}
+
+	// The RPC client is always available for handlers to make remote procedure calls with
+	a.rpcClient()
+
 	// Filters to run before handling the event (but after services have been configured).
 	if a.Filters.Handler.Before != nil {
 		for _, filter := range a.Filters.Handler.Before {
@@ -179,41 +207,21 @@ func (a *Aegis) aegisHandler(ctx context.Context, evt map[string]interface{}) (i
 	return res, err
 }
 
-// RPC makes an Aegis remote procedure call (invokes another Lambda) with tracing support
-func (a *Aegis) RPC(functionName string, message map[string]interface{}) (map[string]interface{}, error) {
-	sess, err := session.NewSession()
-	if err != nil {
-		log.Println("could not make remote procedure call, session could not be created")
-		return nil, err
-	}
-
-	// Payload will need JSON bytes
-	jsonBytes, err := json.Marshal(message)
-	if err != nil {
-		log.Println("could not marshal remote procedure call message")
-		return nil, err
-	}
+// RPC makes an Aegis remote procedure call (invokes another Lambda) with tracing support.
+// Errors from the remote procedure are returned as an *RPCError.
+func (a *Aegis) RPC(functionName string, message map[string]interface{}, opts ...RPCOption) (map[string]interface{}, error) {
+	return a.rpcClient().Invoke(a.TraceContext, functionName, message, opts...)
+}
 
-	// region? cross account?
-	svc := lambdaSDK.New(sess)
-	a.AWSClientTracer(svc.Client)
-
-	// TODO: Look into this more. So many interesting options here. InvocationType and LogType could be interesting outside of defaults
-	output, err := svc.InvokeWithContext(a.TraceContext, &lambdaSDK.InvokeInput{
-		// ClientContext // TODO: think about this...
-		FunctionName: aws.String(functionName),
-		// JSON bytes, sadly it does not pass just any old byte array. It's going to come in as a map to the handler.
-		// That's a map[string]interface{} from JSON. I saw byte array at first and got excited.
-		Payload: jsonBytes,
-		// Qualifier ... this is an interesting one. We use latest by default...But we also want to work in a circuit breaker
-		// here, so we'll need to set the qualifier at some point.
-	})
-
-	// Unmarshal response.
-	var resp map[string]interface{}
-	if err == nil {
-		err = json.Unmarshal(output.Payload, &resp)
+// rpcClient returns the RPC client service, configuring it with the AWS client tracer (for Lambda), logger and tracer if needed
+func (a *Aegis) rpcClient() *RPCClient {
+	if a.Services.RPC == nil {
+		a.Services.RPC = NewRPCClient()
+		if t, ok := a.Services.RPC.Transport.(*LambdaRPCTransport); ok {
+			t.AWSClientTracer = a.AWSClientTracer
+		}
+		a.Services.RPC.Log = a.Log
+		a.Services.RPC.Tracer = &a.Tracer
 	}
-
-	return resp, err
+	return a.Services.RPC
 }
@@ -0,0 +1,152 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// These types are for Application Load Balancer target group events, they are not in the vendored aws-lambda-go package.
+// When multi-value headers are enabled on the target group, only the multi-value fields are sent and expected back.
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/lambda-functions.html
+
+// ALBTargetGroupRequest is the event sent by an Application Load Balancer
+type ALBTargetGroupRequest struct {
+	HTTPMethod                      string                       `json:"httpMethod"`
+	Path                            string                       `json:"path"`
+	QueryStringParameters           map[string]string            `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string          `json:"multiValueQueryStringParameters,omitempty"`
+	Headers                         map[string]string            `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string          `json:"multiValueHeaders,omitempty"`
+	RequestContext                  ALBTargetGroupRequestContext `json:"requestContext"`
+	IsBase64Encoded                 bool                         `json:"isBase64Encoded"`
+	Body                            string                       `json:"body"`
+}
+
+// ALBTargetGroupRequestContext identifies the target group that sent an ALBTargetGroupRequest
+type ALBTargetGroupRequestContext struct {
+	ELB struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	} `json:"elb"`
+}
+
+// ALBTargetGroupResponse is the response for an Application Load Balancer, which requires a status description
+type ALBTargetGroupResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// LambdaHandlerALB handles Application Load Balancer events. The event is converted into an APIGatewayProxyRequest
+// so the same routes, middleware and RouteHandlers are used as with API Gateway.
+func (r *Router) LambdaHandlerALB(ctx context.Context, d *HandlerDependencies, req ALBTargetGroupRequest) (ALBTargetGroupResponse, error) {
+	res, err := r.LambdaHandler(ctx, d, req.proxyRequest())
+	return res.albResponse(req.MultiValueHeaders != nil), err
+}
+
+// proxyRequest converts the load balancer event into an APIGatewayProxyRequest
+func (req *ALBTargetGroupRequest) proxyRequest() APIGatewayProxyRequest {
+	proxyReq := APIGatewayProxyRequest{
+		Path:                  req.Path,
+		HTTPMethod:            req.HTTPMethod,
+		Headers:               map[string]string{},
+		QueryStringParameters: map[string]string{},
+	}
+	proxyReq.RequestContext.HTTPMethod = req.HTTPMethod
+
+	for k, v := range req.Headers {
+		proxyReq.Headers[k] = v
+	}
+	// Like API Gateway, the last value is used. Except for cookies, which are all kept.
+	for k, v := range req.MultiValueHeaders {
+		if len(v) == 0 {
+			continue
+		}
+		if strings.EqualFold(k, HeaderCookie) {
+			proxyReq.Headers[k] = strings.Join(v, "; ")
+			continue
+		}
+		proxyReq.Headers[k] = v[len(v)-1]
+	}
+
+	// The load balancer does not decode querystring parameters, API Gateway does.
+	for k, v := range req.QueryStringParameters {
+		proxyReq.QueryStringParameters[albUnescape(k)] = albUnescape(v)
+	}
+	for k, v := range req.MultiValueQueryStringParameters {
+		if len(v) > 0 {
+			proxyReq.QueryStringParameters[albUnescape(k)] = albUnescape(v[len(v)-1])
+		}
+	}
+
+	proxyReq.Body, proxyReq.IsBase64Encoded = base64Body(req.Body, req.IsBase64Encoded)
+
+	// The client's IP address is the first address in X-Forwarded-For, the load balancer always sets it
+	sourceIP := proxyReq.GetHeader(HeaderXForwardedFor)
+	if i := strings.Index(sourceIP, ","); i > -1 {
+		sourceIP = sourceIP[:i]
+	}
+	proxyReq.RequestContext.Identity.SourceIP = strings.TrimSpace(sourceIP)
+	proxyReq.RequestContext.Identity.UserAgent = proxyReq.GetHeader("User-Agent")
+
+	return proxyReq
+}
+
+// albUnescape will decode a querystring key or value, returning it as is if it isn't valid
+func albUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// albResponse converts the response for an Application Load Balancer. When the target group has multi-value headers
+// enabled, headers are returned as multi-value headers and every Set-Cookie header (see SetCookie()) is kept.
+func (res *APIGatewayProxyResponse) albResponse(multiValueHeaders bool) ALBTargetGroupResponse {
+	albRes := ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode),
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+	}
+	if !multiValueHeaders {
+		albRes.Headers = res.Headers
+		return albRes
+	}
+
+	// Sorted so multiple values are always in the same order
+	keys := make([]string, 0, len(res.Headers))
+	for k := range res.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	albRes.MultiValueHeaders = make(map[string][]string)
+	for _, k := range keys {
+		name := k
+		if strings.EqualFold(k, HeaderSetCookie) {
+			name = HeaderSetCookie
+		}
+		albRes.MultiValueHeaders[name] = append(albRes.MultiValueHeaders[name], res.Headers[k])
+	}
+	return albRes
+}
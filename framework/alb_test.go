@@ -0,0 +1,92 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestALB(t *testing.T) {
+	Convey("proxyRequest", t, func() {
+		Convey("Should convert a single value event", func() {
+			req := ALBTargetGroupRequest{
+				HTTPMethod:            "GET",
+				Path:                  "/pets",
+				QueryStringParameters: map[string]string{"name": "Rex%20Jr"},
+				Headers:               map[string]string{"x-forwarded-for": "203.0.113.10, 10.0.0.1", "user-agent": "curl"},
+			}
+			proxyReq := req.proxyRequest()
+			So(proxyReq.HTTPMethod, ShouldEqual, "GET")
+			So(proxyReq.Path, ShouldEqual, "/pets")
+			So(proxyReq.GetParam("name"), ShouldEqual, "Rex Jr")
+			So(proxyReq.IP(), ShouldEqual, "203.0.113.10")
+			So(proxyReq.UserAgent(), ShouldEqual, "curl")
+		})
+
+		Convey("Should convert a multi-value event", func() {
+			req := ALBTargetGroupRequest{
+				HTTPMethod:                      "POST",
+				Path:                            "/pets",
+				MultiValueQueryStringParameters: map[string][]string{"color": {"brown", "black"}},
+				MultiValueHeaders: map[string][]string{
+					"accept": {"text/html", "application/json"},
+					"cookie": {"session=abc", "theme=dark"},
+				},
+				Body: `{"name":"Rex"}`,
+			}
+			proxyReq := req.proxyRequest()
+			So(proxyReq.GetParam("color"), ShouldEqual, "black")
+			So(proxyReq.GetHeader("Accept"), ShouldEqual, "application/json")
+			cookie, err := proxyReq.Cookie("theme")
+			So(err, ShouldBeNil)
+			So(cookie.Value, ShouldEqual, "dark")
+			body, err := proxyReq.GetJSONBody()
+			So(err, ShouldBeNil)
+			So(body["name"], ShouldEqual, "Rex")
+		})
+	})
+
+	Convey("albResponse", t, func() {
+		res := APIGatewayProxyResponse{}
+		res.String(404, "not found")
+		res.SetCookie(&http.Cookie{Name: "session", Value: "abc"})
+		res.SetCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+		Convey("Should set the status description", func() {
+			So(res.albResponse(false).StatusDescription, ShouldEqual, "404 Not Found")
+		})
+
+		Convey("Should return multi-value headers with every cookie", func() {
+			albRes := res.albResponse(true)
+			So(albRes.Headers, ShouldBeNil)
+			So(albRes.MultiValueHeaders[HeaderContentType], ShouldResemble, []string{MIMETextPlainCharsetUTF8})
+			So(albRes.MultiValueHeaders[HeaderSetCookie], ShouldHaveLength, 2)
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify an ALBTargetGroupRequest", func() {
+			evt := map[string]interface{}{
+				"httpMethod":     "GET",
+				"path":           "/",
+				"requestContext": map[string]interface{}{"elb": map[string]interface{}{"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/aegis/1234"}},
+			}
+			So(getType(evt), ShouldEqual, "ALBTargetGroupRequest")
+		})
+	})
+}
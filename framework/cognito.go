@@ -16,6 +16,7 @@ package framework
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -132,15 +133,133 @@ func (r *CognitoRouter) Handle(triggerSource string, handler func(context.Contex
 	r.handlers[triggerSource] = handler
 }
 
-// PreSignUp is the same as Handle only the triggerSource is already implied. It handles any PreSignUp_SignUp event.
-func (r *CognitoRouter) PreSignUp(handler func(context.Context, *HandlerDependencies, map[string]interface{}) (map[string]interface{}, error)) {
-	r.Handle("PreSignUp_SignUp", handler)
+// handleTrigger registers a handler for the given trigger sources, or every source of the trigger when none are given
+func (r *CognitoRouter) handleTrigger(trigger string, triggerSources []string, handler CognitoHandler) {
+	if len(triggerSources) == 0 {
+		triggerSources = cognitoTriggerSources[trigger]
+	}
+	for _, triggerSource := range triggerSources {
+		r.Handle(triggerSource, handler)
+	}
+}
+
+// typedCognitoHandler decodes the event into a trigger struct (which must be a pointer), calls the handler and then
+// returns the re-encoded trigger struct as the response. Cognito expects the whole event back, with its response set.
+func typedCognitoHandler(evt map[string]interface{}, trigger interface{}, handler func() error) (map[string]interface{}, error) {
+	if err := decodeJSONEvent(evt, trigger); err != nil {
+		log.Println("Could not decode Cognito trigger", err)
+		return nil, err
+	}
+	if err := handler(); err != nil {
+		return nil, err
+	}
+	var response map[string]interface{}
+	b, err := json.Marshal(trigger)
+	if err == nil {
+		err = json.Unmarshal(b, &response)
+	}
+	return response, err
+}
+
+// PreSignUp will register a handler for PreSignUp triggers, optionally only for the given trigger sources
+// (PreSignUp_SignUp, PreSignUp_AdminCreateUser, PreSignUp_ExternalProvider)
+func (r *CognitoRouter) PreSignUp(handler func(context.Context, *HandlerDependencies, *CognitoTriggerPreSignup) error, triggerSources ...string) {
+	r.handleTrigger("PreSignUp", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerPreSignup
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// PostConfirmation will register a handler for PostConfirmation triggers, optionally only for the given trigger sources
+// (PostConfirmation_ConfirmSignUp, PostConfirmation_ConfirmForgotPassword)
+func (r *CognitoRouter) PostConfirmation(handler func(context.Context, *HandlerDependencies, *CognitoTriggerPostConfirmation) error, triggerSources ...string) {
+	r.handleTrigger("PostConfirmation", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerPostConfirmation
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// PreAuthentication will register a handler for the PreAuthentication_Authentication trigger
+func (r *CognitoRouter) PreAuthentication(handler func(context.Context, *HandlerDependencies, *CognitoTriggerPreAuthentication) error) {
+	r.handleTrigger("PreAuthentication", nil, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerPreAuthentication
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
 }
 
-// Too many?
-// CustomMessage_ResendCode
-// func (r *CognitoRouter) ResendCodeMessage(handler func(context.Context, *map[string]interface{}) error) {
-// 	r.Handle("CustomMessage_ResendCode", handler)
-// }
+// PostAuthentication will register a handler for the PostAuthentication_Authentication trigger
+func (r *CognitoRouter) PostAuthentication(handler func(context.Context, *HandlerDependencies, *CognitoTriggerPostAuthentication) error) {
+	r.handleTrigger("PostAuthentication", nil, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerPostAuthentication
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
 
-// PreAuthentication_Authentication
+// CustomMessage will register a handler for CustomMessage triggers, optionally only for the given trigger sources
+// (ie. CustomMessage_SignUp, CustomMessage_ForgotPassword, etc.)
+func (r *CognitoRouter) CustomMessage(handler func(context.Context, *HandlerDependencies, *CognitoTriggerCustomMessage) error, triggerSources ...string) {
+	r.handleTrigger("CustomMessage", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerCustomMessage
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// PreTokenGeneration will register a handler for TokenGeneration triggers, optionally only for the given trigger sources
+// (ie. TokenGeneration_HostedAuth, TokenGeneration_RefreshTokens, etc.)
+func (r *CognitoRouter) PreTokenGeneration(handler func(context.Context, *HandlerDependencies, *CognitoTriggerTokenGeneration) error, triggerSources ...string) {
+	r.handleTrigger("TokenGeneration", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerTokenGeneration
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// DefineAuthChallenge will register a handler for the DefineAuthChallenge_Authentication trigger
+func (r *CognitoRouter) DefineAuthChallenge(handler func(context.Context, *HandlerDependencies, *CognitoTriggerDefineAuthChallenge) error) {
+	r.handleTrigger("DefineAuthChallenge", nil, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerDefineAuthChallenge
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// CreateAuthChallenge will register a handler for the CreateAuthChallenge_Authentication trigger
+func (r *CognitoRouter) CreateAuthChallenge(handler func(context.Context, *HandlerDependencies, *CognitoTriggerCreateAuthChallenge) error) {
+	r.handleTrigger("CreateAuthChallenge", nil, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerCreateAuthChallenge
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// VerifyAuthChallengeResponse will register a handler for the VerifyAuthChallengeResponse_Authentication trigger
+func (r *CognitoRouter) VerifyAuthChallengeResponse(handler func(context.Context, *HandlerDependencies, *CognitoTriggerVerifyAuthChallengeResponse) error) {
+	r.handleTrigger("VerifyAuthChallengeResponse", nil, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerVerifyAuthChallengeResponse
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// UserMigration will register a handler for UserMigration triggers, optionally only for the given trigger sources
+// (UserMigration_Authentication, UserMigration_ForgotPassword)
+func (r *CognitoRouter) UserMigration(handler func(context.Context, *HandlerDependencies, *CognitoTriggerUserMigration) error, triggerSources ...string) {
+	r.handleTrigger("UserMigration", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerUserMigration
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// CustomSMSSender will register a handler for CustomSMSSender triggers, optionally only for the given trigger sources
+// (ie. CustomSMSSender_SignUp, CustomSMSSender_Authentication, etc.)
+func (r *CognitoRouter) CustomSMSSender(handler func(context.Context, *HandlerDependencies, *CognitoTriggerCustomSender) error, triggerSources ...string) {
+	r.handleTrigger("CustomSMSSender", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerCustomSender
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
+
+// CustomEmailSender will register a handler for CustomEmailSender triggers, optionally only for the given trigger sources
+// (ie. CustomEmailSender_SignUp, CustomEmailSender_ForgotPassword, etc.)
+func (r *CognitoRouter) CustomEmailSender(handler func(context.Context, *HandlerDependencies, *CognitoTriggerCustomSender) error, triggerSources ...string) {
+	r.handleTrigger("CustomEmailSender", triggerSources, func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		var trigger CognitoTriggerCustomSender
+		return typedCognitoHandler(evt, &trigger, func() error { return handler(ctx, d, &trigger) })
+	})
+}
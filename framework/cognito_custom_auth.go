@@ -0,0 +1,273 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+)
+
+// CustomAuthFlow generates the Cognito custom authentication triggers (DefineAuthChallenge, CreateAuthChallenge and
+// VerifyAuthChallengeResponse) for a sequence of challenges, ie. an emailed one time code followed by a security question.
+// Users must answer every challenge, in order, to be issued tokens. When signing in with SRP, the password is
+// verified first (PASSWORD_VERIFIER) for a "password then one time code" flow.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/user-pool-lambda-challenge.html
+type CustomAuthFlow struct {
+	Challenges []*AuthChallenge
+	// MaxAttempts is how many times each challenge can be answered incorrectly before authentication fails (default 3)
+	MaxAttempts int
+}
+
+// AuthChallenge is a single step of a CustomAuthFlow
+type AuthChallenge struct {
+	// Name identifies the challenge, it's sent to the client (as the "challenge" public parameter) and kept as challenge metadata
+	Name string
+	// Generator returns the expected answer for the challenge, ie. a random code
+	Generator AuthCodeGenerator
+	// Sender delivers the code to the user, it's optional (a security question's answer should not be sent anywhere)
+	Sender AuthCodeSender
+	// PublicParameters returns additional parameters for the client, ie. the security question to display (optional)
+	PublicParameters func(*CognitoTriggerCreateAuthChallenge) map[string]string
+	// Verify compares the expected answer with the user's answer (optional, by default the two must be identical)
+	Verify func(expected string, answer string) bool
+}
+
+// AuthCodeGenerator returns the expected answer for a challenge
+type AuthCodeGenerator func(context.Context, *CognitoTriggerCreateAuthChallenge) (string, error)
+
+// AuthCodeSender delivers a challenge's code to the user, ie. by email or SMS
+type AuthCodeSender interface {
+	SendCode(ctx context.Context, evt *CognitoTriggerCreateAuthChallenge, code string) error
+}
+
+// AuthCodeSenderFunc allows an ordinary function to be used as an AuthCodeSender
+type AuthCodeSenderFunc func(ctx context.Context, evt *CognitoTriggerCreateAuthChallenge, code string) error
+
+// SendCode calls f(ctx, evt, code)
+func (f AuthCodeSenderFunc) SendCode(ctx context.Context, evt *CognitoTriggerCreateAuthChallenge, code string) error {
+	return f(ctx, evt, code)
+}
+
+// Challenge names Cognito uses for custom challenges and for verifying a password (with SRP) before them
+const (
+	cognitoCustomChallenge  = "CUSTOM_CHALLENGE"
+	cognitoSRPA             = "SRP_A"
+	cognitoPasswordVerifier = "PASSWORD_VERIFIER"
+)
+
+// Challenge parameter names
+const (
+	authChallengeParameter = "challenge"
+	authAnswerParameter    = "answer"
+)
+
+// NewCustomAuthFlow returns a new CustomAuthFlow for the given challenges, which will be presented in order
+func NewCustomAuthFlow(challenges ...*AuthChallenge) *CustomAuthFlow {
+	return &CustomAuthFlow{
+		Challenges:  challenges,
+		MaxAttempts: 3,
+	}
+}
+
+// CustomAuth will register the DefineAuthChallenge, CreateAuthChallenge and VerifyAuthChallengeResponse handlers for a CustomAuthFlow
+func (r *CognitoRouter) CustomAuth(flow *CustomAuthFlow) {
+	r.DefineAuthChallenge(flow.DefineAuthChallenge)
+	r.CreateAuthChallenge(flow.CreateAuthChallenge)
+	r.VerifyAuthChallengeResponse(flow.VerifyAuthChallengeResponse)
+}
+
+// progress walks through the challenges answered so far in the session and returns the index of the current challenge.
+// It returns false if the session isn't valid for the flow or if the current challenge has been failed too many times.
+// A successfully verified password (SRP_A then PASSWORD_VERIFIER) can come before the custom challenges.
+func (f *CustomAuthFlow) progress(session []CognitoTriggerChallengeResult) (int, bool) {
+	maxAttempts := f.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	current, attempts := 0, 0
+	for _, result := range session {
+		passwordStep := result.ChallengeName == cognitoSRPA || result.ChallengeName == cognitoPasswordVerifier
+		if passwordStep && result.ChallengeResult && current == 0 && attempts == 0 {
+			continue
+		}
+		if result.ChallengeName != cognitoCustomChallenge || current >= len(f.Challenges) || result.ChallengeMetadata != f.Challenges[current].Name {
+			return current, false
+		}
+		if result.ChallengeResult {
+			current++
+			attempts = 0
+			continue
+		}
+		attempts++
+		if attempts >= maxAttempts {
+			return current, false
+		}
+	}
+	return current, true
+}
+
+// challenge returns the challenge with the given name
+func (f *CustomAuthFlow) challenge(name string) *AuthChallenge {
+	for _, c := range f.Challenges {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// DefineAuthChallenge decides whether to present the next challenge, issue tokens or fail the authentication
+func (f *CustomAuthFlow) DefineAuthChallenge(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerDefineAuthChallenge) error {
+	current, ok := f.progress(evt.Request.Session)
+	session := evt.Request.Session
+	switch {
+	case !ok || evt.Request.UserNotFound || len(f.Challenges) == 0:
+		evt.Response.FailAuthentication = true
+	case len(session) > 0 && session[len(session)-1].ChallengeName == cognitoSRPA:
+		// The password has to be verified before the custom challenges
+		evt.Response.ChallengeName = cognitoPasswordVerifier
+	case current == len(f.Challenges):
+		evt.Response.IssueTokens = true
+	default:
+		evt.Response.ChallengeName = cognitoCustomChallenge
+	}
+	return nil
+}
+
+// CreateAuthChallenge generates (and sends) the code for the current challenge
+func (f *CustomAuthFlow) CreateAuthChallenge(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerCreateAuthChallenge) error {
+	current, ok := f.progress(evt.Request.Session)
+	if !ok || current >= len(f.Challenges) {
+		return errors.New("no challenge to create for the authentication session")
+	}
+	c := f.Challenges[current]
+	if c.Generator == nil {
+		return fmt.Errorf("challenge %s has no code generator", c.Name)
+	}
+
+	code, err := c.Generator(ctx, evt)
+	if err != nil {
+		return err
+	}
+	if c.Sender != nil {
+		if err := c.Sender.SendCode(ctx, evt, code); err != nil {
+			log.Println("Could not send code for challenge", c.Name, err)
+			return err
+		}
+	}
+
+	evt.Response.PublicChallengeParameters = map[string]string{}
+	if c.PublicParameters != nil {
+		for k, v := range c.PublicParameters(evt) {
+			evt.Response.PublicChallengeParameters[k] = v
+		}
+	}
+	evt.Response.PublicChallengeParameters[authChallengeParameter] = c.Name
+	evt.Response.PrivateChallengeParameters = map[string]string{
+		authChallengeParameter: c.Name,
+		authAnswerParameter:    code,
+	}
+	evt.Response.ChallengeMetadata = c.Name
+	return nil
+}
+
+// VerifyAuthChallengeResponse checks the user's answer to the current challenge
+func (f *CustomAuthFlow) VerifyAuthChallengeResponse(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerVerifyAuthChallengeResponse) error {
+	c := f.challenge(evt.Request.PrivateChallengeParameters[authChallengeParameter])
+	if c == nil {
+		evt.Response.AnswerCorrect = false
+		return nil
+	}
+	expected := evt.Request.PrivateChallengeParameters[authAnswerParameter]
+	if c.Verify != nil {
+		evt.Response.AnswerCorrect = c.Verify(expected, evt.Request.ChallengeAnswer)
+		return nil
+	}
+	evt.Response.AnswerCorrect = expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(evt.Request.ChallengeAnswer)) == 1
+	return nil
+}
+
+// NumericCodeGenerator returns an AuthCodeGenerator for random one time codes of the given number of digits
+func NumericCodeGenerator(digits int) AuthCodeGenerator {
+	return func(context.Context, *CognitoTriggerCreateAuthChallenge) (string, error) {
+		code := make([]byte, digits)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, big.NewInt(10))
+			if err != nil {
+				return "", err
+			}
+			code[i] = byte('0' + n.Int64())
+		}
+		return string(code), nil
+	}
+}
+
+// UserAttributeAnswer returns an AuthCodeGenerator whose answer is the value of a user attribute,
+// ie. "custom:security_answer" for a security question
+func UserAttributeAnswer(attribute string) AuthCodeGenerator {
+	return func(ctx context.Context, evt *CognitoTriggerCreateAuthChallenge) (string, error) {
+		answer, ok := evt.Request.UserAttributes[attribute].(string)
+		if !ok || answer == "" {
+			return "", fmt.Errorf("user attribute %s is not set", attribute)
+		}
+		return answer, nil
+	}
+}
+
+// LocalAuthCodeSender is a stand-in AuthCodeSender for local development and tests. Instead of delivering codes,
+// it logs them and keeps them by user name.
+type LocalAuthCodeSender struct {
+	mu    sync.Mutex
+	codes map[string][]string
+	// Quiet will stop codes from being logged
+	Quiet bool
+}
+
+// SendCode keeps the code for the user
+func (s *LocalAuthCodeSender) SendCode(ctx context.Context, evt *CognitoTriggerCreateAuthChallenge, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.codes == nil {
+		s.codes = make(map[string][]string)
+	}
+	s.codes[evt.UserName] = append(s.codes[evt.UserName], code)
+	if !s.Quiet {
+		log.Printf("code for %s: %s\n", evt.UserName, code)
+	}
+	return nil
+}
+
+// LastCode returns the last code sent to a user, or an empty string if none were sent
+func (s *LocalAuthCodeSender) LastCode(userName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if codes := s.codes[userName]; len(codes) > 0 {
+		return codes[len(codes)-1]
+	}
+	return ""
+}
+
+// Codes returns every code sent to a user
+func (s *LocalAuthCodeSender) Codes(userName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.codes[userName]...)
+}
@@ -0,0 +1,145 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCustomAuthFlow(t *testing.T) {
+	sender := &LocalAuthCodeSender{Quiet: true}
+	flow := NewCustomAuthFlow(
+		&AuthChallenge{Name: "email-otp", Generator: NumericCodeGenerator(6), Sender: sender},
+		&AuthChallenge{
+			Name:      "security-question",
+			Generator: UserAttributeAnswer("custom:answer"),
+			PublicParameters: func(evt *CognitoTriggerCreateAuthChallenge) map[string]string {
+				return map[string]string{"question": "Favorite color?"}
+			},
+		},
+	)
+	ctx := context.Background()
+	d := &HandlerDependencies{}
+	userAttributes := map[string]interface{}{"email": "jane@example.com", "custom:answer": "blue"}
+
+	define := func(session []CognitoTriggerChallengeResult) *CognitoTriggerDefineAuthChallenge {
+		evt := &CognitoTriggerDefineAuthChallenge{}
+		evt.UserName = "jane"
+		evt.Request.Session = session
+		flow.DefineAuthChallenge(ctx, d, evt)
+		return evt
+	}
+	// Runs the create and verify triggers like Cognito would for one answer, returning the updated session
+	answer := func(session []CognitoTriggerChallengeResult, answerFor func(*CognitoTriggerCreateAuthChallenge) string) []CognitoTriggerChallengeResult {
+		create := &CognitoTriggerCreateAuthChallenge{}
+		create.UserName = "jane"
+		create.Request.UserAttributes = userAttributes
+		create.Request.ChallengeName = cognitoCustomChallenge
+		create.Request.Session = session
+		So(flow.CreateAuthChallenge(ctx, d, create), ShouldBeNil)
+
+		verify := &CognitoTriggerVerifyAuthChallengeResponse{}
+		verify.Request.PrivateChallengeParameters = create.Response.PrivateChallengeParameters
+		verify.Request.ChallengeAnswer = answerFor(create)
+		flow.VerifyAuthChallengeResponse(ctx, d, verify)
+
+		return append(session, CognitoTriggerChallengeResult{
+			ChallengeName:     cognitoCustomChallenge,
+			ChallengeResult:   verify.Response.AnswerCorrect,
+			ChallengeMetadata: create.Response.ChallengeMetadata,
+		})
+	}
+	emailCode := func(*CognitoTriggerCreateAuthChallenge) string { return sender.LastCode("jane") }
+	securityAnswer := func(create *CognitoTriggerCreateAuthChallenge) string {
+		So(create.Response.PublicChallengeParameters["question"], ShouldEqual, "Favorite color?")
+		return "blue"
+	}
+	wrong := func(*CognitoTriggerCreateAuthChallenge) string { return "wrong" }
+
+	Convey("CustomAuthFlow", t, func() {
+		Convey("Should present the first challenge", func() {
+			evt := define(nil)
+			So(evt.Response.ChallengeName, ShouldEqual, cognitoCustomChallenge)
+			So(evt.Response.IssueTokens, ShouldBeFalse)
+			So(evt.Response.FailAuthentication, ShouldBeFalse)
+		})
+
+		Convey("Should issue tokens once every challenge is answered", func() {
+			session := answer(nil, emailCode)
+			So(sender.LastCode("jane"), ShouldHaveLength, 6)
+			So(define(session).Response.ChallengeName, ShouldEqual, cognitoCustomChallenge)
+			session = answer(session, securityAnswer)
+			So(define(session).Response.IssueTokens, ShouldBeTrue)
+		})
+
+		Convey("Should allow retries then fail after too many wrong answers", func() {
+			session := answer(nil, wrong)
+			session = answer(session, wrong)
+			So(define(session).Response.FailAuthentication, ShouldBeFalse)
+			session = answer(session, wrong)
+			So(define(session).Response.FailAuthentication, ShouldBeTrue)
+		})
+
+		Convey("Should fail for unknown users", func() {
+			evt := &CognitoTriggerDefineAuthChallenge{}
+			evt.Request.UserNotFound = true
+			flow.DefineAuthChallenge(ctx, d, evt)
+			So(evt.Response.FailAuthentication, ShouldBeTrue)
+		})
+
+		Convey("Should fail when the session does not follow the flow", func() {
+			session := []CognitoTriggerChallengeResult{{ChallengeName: cognitoCustomChallenge, ChallengeResult: true, ChallengeMetadata: "security-question"}}
+			So(define(session).Response.FailAuthentication, ShouldBeTrue)
+
+			session = []CognitoTriggerChallengeResult{{ChallengeName: "SMS_MFA", ChallengeResult: true}}
+			So(define(session).Response.FailAuthentication, ShouldBeTrue)
+		})
+
+		Convey("Should verify a password before the custom challenges", func() {
+			session := []CognitoTriggerChallengeResult{{ChallengeName: cognitoSRPA, ChallengeResult: true}}
+			evt := define(session)
+			So(evt.Response.FailAuthentication, ShouldBeFalse)
+			So(evt.Response.ChallengeName, ShouldEqual, cognitoPasswordVerifier)
+
+			session = append(session, CognitoTriggerChallengeResult{ChallengeName: cognitoPasswordVerifier, ChallengeResult: true})
+			evt = define(session)
+			So(evt.Response.FailAuthentication, ShouldBeFalse)
+			So(evt.Response.ChallengeName, ShouldEqual, cognitoCustomChallenge)
+
+			session = answer(session, emailCode)
+			session = answer(session, securityAnswer)
+			So(define(session).Response.IssueTokens, ShouldBeTrue)
+		})
+
+		Convey("Should fail when the password is wrong", func() {
+			session := []CognitoTriggerChallengeResult{
+				{ChallengeName: cognitoSRPA, ChallengeResult: true},
+				{ChallengeName: cognitoPasswordVerifier, ChallengeResult: false},
+			}
+			So(define(session).Response.FailAuthentication, ShouldBeTrue)
+		})
+
+		Convey("Should register the custom auth triggers with a CognitoRouter", func() {
+			r := NewCognitoRouter()
+			r.CustomAuth(flow)
+			So(r.handlers, ShouldContainKey, "DefineAuthChallenge_Authentication")
+			So(r.handlers, ShouldContainKey, "CreateAuthChallenge_Authentication")
+			So(r.handlers, ShouldContainKey, "VerifyAuthChallengeResponse_Authentication")
+		})
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// CognitoSyncRouter struct provides an interface to handle Cognito Sync events (routed by identity pool and dataset name)
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-events.html
+type CognitoSyncRouter struct {
+	handlers map[string]CognitoSyncHandler
+	Tracer   TraceStrategy
+}
+
+// CognitoSyncHandler handles routed Cognito Sync events. Changes made to the event's dataset records
+// are returned to Cognito Sync, which will then save them.
+type CognitoSyncHandler func(context.Context, *HandlerDependencies, *CognitoEvent) error
+
+// Cognito Sync dataset record operations
+const (
+	CognitoSyncReplace = "replace"
+	CognitoSyncRemove  = "remove"
+)
+
+// LambdaHandler handles Cognito Sync events. The event is returned, with any changes to its dataset records, as the response.
+func (r *CognitoSyncRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt CognitoEvent) (CognitoEvent, error) {
+	handler, ok := r.handlerFor(&evt)
+	r.Tracer.Annotations = map[string]interface{}{
+		"CognitoIdentityPoolID": evt.IdentityPoolID,
+		"CognitoDatasetName":    evt.DatasetName,
+		"CognitoEventType":      evt.EventType,
+		"CognitoIdentityID":     evt.IdentityID,
+	}
+	if !ok {
+		log.Println("using default fall through handler")
+		r.Tracer.Annotations["FallthroughHandler"] = true
+	}
+
+	err := r.Tracer.Capture(ctx, "CognitoSyncHandler", func(ctx1 context.Context) error {
+		r.Tracer.AddAnnotations(ctx1)
+		r.Tracer.AddMetadata(ctx1)
+		d.Tracer = &r.Tracer
+		return handler(ctx1, d, &evt)
+	})
+
+	return evt, err
+}
+
+// handlerFor returns the most specific handler for the event's identity pool and dataset name, or the fall through
+// handler (with false) when nothing matched. A handler for a pool and dataset is preferred over one for a dataset
+// in any pool, which is preferred over one for any dataset in a pool.
+func (r *CognitoSyncRouter) handlerFor(evt *CognitoEvent) (CognitoSyncHandler, bool) {
+	for _, key := range []string{
+		cognitoSyncKey(evt.IdentityPoolID, evt.DatasetName),
+		cognitoSyncKey("*", evt.DatasetName),
+		cognitoSyncKey(evt.IdentityPoolID, "*"),
+	} {
+		if handler, ok := r.handlers[key]; ok {
+			return handler, true
+		}
+	}
+
+	// It's possible that the CognitoSyncRouter wasn't created with NewCognitoSyncRouter, so check for this still.
+	if handler, ok := r.handlers["*"]; ok {
+		return handler, false
+	}
+	return func(context.Context, *HandlerDependencies, *CognitoEvent) error { return nil }, false
+}
+
+// cognitoSyncKey returns the handlers map key for an identity pool and dataset name
+func cognitoSyncKey(identityPoolID string, datasetName string) string {
+	return identityPoolID + "/" + datasetName
+}
+
+// SetRecord will set the new value for a dataset record
+func (evt *CognitoEvent) SetRecord(key string, value string) {
+	if evt.DatasetRecords == nil {
+		evt.DatasetRecords = make(map[string]events.CognitoDatasetRecord)
+	}
+	record := evt.DatasetRecords[key]
+	record.NewValue = value
+	record.Op = CognitoSyncReplace
+	evt.DatasetRecords[key] = record
+}
+
+// RemoveRecord will remove a dataset record
+func (evt *CognitoEvent) RemoveRecord(key string) {
+	if record, ok := evt.DatasetRecords[key]; ok {
+		record.NewValue = ""
+		record.Op = CognitoSyncRemove
+		evt.DatasetRecords[key] = record
+	}
+}
+
+// Listen will start a Cognito Sync listener that handles incoming events
+func (r *CognitoSyncRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewCognitoSyncRouter simply returns a new CognitoSyncRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewCognitoSyncRouter(rootHandler ...CognitoSyncHandler) *CognitoSyncRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and the records are returned unchanged.
+	handler := func(context.Context, *HandlerDependencies, *CognitoEvent) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &CognitoSyncRouter{
+		handlers: map[string]CognitoSyncHandler{
+			"*": handler,
+		},
+	}
+}
+
+// Handle will register a handler for a given identity pool ID and dataset name, either can be "*" to match any
+func (r *CognitoSyncRouter) Handle(identityPoolID string, datasetName string, handler CognitoSyncHandler) {
+	if r.handlers == nil {
+		r.handlers = make(map[string]CognitoSyncHandler)
+	}
+	r.handlers[cognitoSyncKey(identityPoolID, datasetName)] = handler
+}
+
+// HandleDataset is the same as Handle only for a dataset in any identity pool
+func (r *CognitoSyncRouter) HandleDataset(datasetName string, handler CognitoSyncHandler) {
+	r.Handle("*", datasetName, handler)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCognitoSyncRouter(t *testing.T) {
+	routeHandled := ""
+	newHandler := func(name string) CognitoSyncHandler {
+		return func(ctx context.Context, d *HandlerDependencies, evt *CognitoEvent) error {
+			routeHandled = name
+			return nil
+		}
+	}
+
+	testRouter := NewCognitoSyncRouter()
+	Convey("NewCognitoSyncRouter", t, func() {
+		Convey("Should create a new CognitoSyncRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Handle("us-east-1:pool", "settings", newHandler("pool settings"))
+	testRouter.HandleDataset("settings", newHandler("any settings"))
+	testRouter.Handle("us-east-1:pool", "*", newHandler("pool"))
+
+	Convey("handlerFor", t, func() {
+		Convey("Should prefer the identity pool and dataset", func() {
+			handler, ok := testRouter.handlerFor(&CognitoEvent{IdentityPoolID: "us-east-1:pool", DatasetName: "settings"})
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &CognitoEvent{})
+			So(routeHandled, ShouldEqual, "pool settings")
+		})
+
+		Convey("Should match a dataset in any identity pool", func() {
+			handler, ok := testRouter.handlerFor(&CognitoEvent{IdentityPoolID: "us-east-1:other", DatasetName: "settings"})
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &CognitoEvent{})
+			So(routeHandled, ShouldEqual, "any settings")
+		})
+
+		Convey("Should match any dataset in an identity pool", func() {
+			handler, ok := testRouter.handlerFor(&CognitoEvent{IdentityPoolID: "us-east-1:pool", DatasetName: "scores"})
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &CognitoEvent{})
+			So(routeHandled, ShouldEqual, "pool")
+		})
+
+		Convey("Should fall through when nothing matches", func() {
+			_, ok := testRouter.handlerFor(&CognitoEvent{IdentityPoolID: "us-east-1:other", DatasetName: "scores"})
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Dataset records", t, func() {
+		evt := CognitoEvent{DatasetRecords: map[string]events.CognitoDatasetRecord{
+			"theme": {OldValue: "light", NewValue: "dark", Op: "replace"},
+		}}
+
+		Convey("Should set a record", func() {
+			evt.SetRecord("theme", "solarized")
+			So(evt.DatasetRecords["theme"].NewValue, ShouldEqual, "solarized")
+			So(evt.DatasetRecords["theme"].OldValue, ShouldEqual, "light")
+		})
+
+		Convey("Should remove a record", func() {
+			evt.RemoveRecord("theme")
+			So(evt.DatasetRecords["theme"].Op, ShouldEqual, CognitoSyncRemove)
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify a CognitoEvent", func() {
+			evt := map[string]interface{}{"identityPoolId": "us-east-1:pool", "datasetRecords": map[string]interface{}{}}
+			So(getType(evt), ShouldEqual, "CognitoEvent")
+		})
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCognitoRouter(t *testing.T) {
+	newEvent := func(triggerSource string, request map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"version":       "1",
+			"triggerSource": triggerSource,
+			"region":        "us-east-1",
+			"userPoolId":    "us-east-1_abc",
+			"userName":      "jane",
+			"callerContext": map[string]interface{}{"awsSdkVersion": "1", "clientId": "client"},
+			"request":       request,
+			"response":      map[string]interface{}{},
+		}
+	}
+
+	testRouter := NewCognitoRouter()
+	testRouter.PreSignUp(func(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerPreSignup) error {
+		if evt.Request.UserAttributes["email"] == "blocked@example.com" {
+			return errors.New("sign up not allowed")
+		}
+		evt.Response.AutoConfirmUser = true
+		return nil
+	})
+	testRouter.CustomMessage(func(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerCustomMessage) error {
+		evt.Response.EmailSubject = "Reset your password"
+		evt.Response.EmailMessage = "Your code is " + evt.Request.CodeParameter
+		return nil
+	}, "CustomMessage_ForgotPassword")
+	sentCode := ""
+	testRouter.CustomEmailSender(func(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerCustomSender) error {
+		sentCode = evt.Request.Code
+		return nil
+	})
+	testRouter.PreTokenGeneration(func(ctx context.Context, d *HandlerDependencies, evt *CognitoTriggerTokenGeneration) error {
+		evt.OverrideClaim("tenant", "acme")
+		evt.SuppressClaim("email")
+		return nil
+	})
+
+	Convey("Typed handlers", t, func() {
+		Convey("Should register every trigger source of a trigger", func() {
+			for _, source := range []string{"PreSignUp_SignUp", "PreSignUp_AdminCreateUser", "PreSignUp_ExternalProvider"} {
+				So(testRouter.handlers, ShouldContainKey, source)
+			}
+		})
+
+		Convey("Should only register the given trigger sources", func() {
+			So(testRouter.handlers, ShouldContainKey, "CustomMessage_ForgotPassword")
+			So(testRouter.handlers, ShouldNotContainKey, "CustomMessage_SignUp")
+		})
+
+		Convey("Should decode the event and return it with the typed response", func() {
+			evt := newEvent("PreSignUp_SignUp", map[string]interface{}{"userAttributes": map[string]interface{}{"email": "jane@example.com"}})
+			res, err := testRouter.handlers["PreSignUp_SignUp"](context.Background(), &HandlerDependencies{}, evt)
+			So(err, ShouldBeNil)
+			So(res["userName"], ShouldEqual, "jane")
+			So(res["response"].(map[string]interface{})["autoConfirmUser"], ShouldBeTrue)
+		})
+
+		Convey("Should return the handler's error", func() {
+			evt := newEvent("PreSignUp_SignUp", map[string]interface{}{"userAttributes": map[string]interface{}{"email": "blocked@example.com"}})
+			_, err := testRouter.handlers["PreSignUp_SignUp"](context.Background(), &HandlerDependencies{}, evt)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should set custom messages", func() {
+			evt := newEvent("CustomMessage_ForgotPassword", map[string]interface{}{"codeParameter": "{####}"})
+			res, _ := testRouter.handlers["CustomMessage_ForgotPassword"](context.Background(), &HandlerDependencies{}, evt)
+			response := res["response"].(map[string]interface{})
+			So(response["emailMessage"], ShouldEqual, "Your code is {####}")
+			So(response, ShouldNotContainKey, "smsMessage")
+		})
+
+		Convey("Should handle custom sender triggers", func() {
+			So(testRouter.handlers, ShouldContainKey, "CustomEmailSender_AccountTakeOverNotification")
+			So(testRouter.handlers, ShouldNotContainKey, "CustomSMSSender_SignUp")
+
+			evt := newEvent("CustomEmailSender_SignUp", map[string]interface{}{"type": "customEmailSenderRequestV1", "code": "AYADeBgAAAAA"})
+			_, err := testRouter.handlers["CustomEmailSender_SignUp"](context.Background(), &HandlerDependencies{}, evt)
+			So(err, ShouldBeNil)
+			So(sentCode, ShouldEqual, "AYADeBgAAAAA")
+		})
+
+		Convey("Should set claim overrides", func() {
+			evt := newEvent("TokenGeneration_RefreshTokens", map[string]interface{}{})
+			res, _ := testRouter.handlers["TokenGeneration_RefreshTokens"](context.Background(), &HandlerDependencies{}, evt)
+			details := res["response"].(map[string]interface{})["claimsOverrideDetails"].(map[string]interface{})
+			So(details["claimsToAddOrOverride"].(map[string]interface{})["tenant"], ShouldEqual, "acme")
+			So(details["claimsToSuppress"], ShouldResemble, []interface{}{"email"})
+		})
+	})
+
+	Convey("GetCognitoTriggerType", t, func() {
+		Convey("Should return the struct name for every trigger source", func() {
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "CustomMessage_Authentication"}), ShouldEqual, "CognitoTriggerCustomMessage")
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "TokenGeneration_NewPasswordChallenge"}), ShouldEqual, "CognitoTriggerTokenGeneration")
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "VerifyAuthChallengeResponse_Authentication"}), ShouldEqual, "CognitoTriggerVerifyAuthChallengeResponse")
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "UserMigration_ForgotPassword"}), ShouldEqual, "CognitoTriggerUserMigration")
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "CustomSMSSender_Authentication"}), ShouldEqual, "CognitoTriggerCustomSender")
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "CustomEmailSender_ForgotPassword"}), ShouldEqual, "CognitoTriggerCustomSender")
+		})
+
+		Convey("Should return an empty string for unknown trigger sources", func() {
+			So(GetCognitoTriggerType(map[string]interface{}{"triggerSource": "CustomMessage_Unknown"}), ShouldEqual, "")
+			So(GetCognitoTriggerType(map[string]interface{}{}), ShouldEqual, "")
+		})
+	})
+}
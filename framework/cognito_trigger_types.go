@@ -14,6 +14,8 @@
 
 package framework
 
+import "strings"
+
 // These types are not currently in the aws-lambda-go package (though there's a pull request for partial support).
 // The request and response structure depends on the trigger.
 // https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-pools-lambda-trigger-syntax-shared.html
@@ -36,12 +38,13 @@ type CognitoTriggerCommon struct {
 
 // CognitoTriggerPreSignup is invoked when a user submits their information to sign up, allowing you to perform
 // custom validation to accept or deny the sign up request.
-// triggerSource: PreSignUp_AdminCreateUser, PreSignUp_SignUp
+// triggerSource: PreSignUp_SignUp, PreSignUp_AdminCreateUser, PreSignUp_ExternalProvider
 type CognitoTriggerPreSignup struct {
 	CognitoTriggerCommon
 	Request struct {
 		UserAttributes map[string]interface{} `json:"userAttributes"`
 		ValidationData map[string]interface{} `json:"validationData"`
+		ClientMetadata map[string]string      `json:"clientMetadata,omitempty"`
 	} `json:"request"`
 	Response struct {
 		AutoConfirmUser bool `json:"autoConfirmUser"`
@@ -57,24 +60,29 @@ type CognitoTriggerPostConfirmation struct {
 	CognitoTriggerCommon
 	Request struct {
 		UserAttributes map[string]interface{} `json:"userAttributes"`
+		ClientMetadata map[string]string      `json:"clientMetadata,omitempty"`
 	} `json:"request"`
 	Response map[string]interface{} `json:"response"`
 }
 
 // CognitoTriggerCustomMessage is invoked before a verification or MFA message is sent, allowing you to
 // customize the message dynamically. Note that static custom messages can be edited on the Verifications panel.
-// triggerSource: CustomMessage_ResendCode
+// Messages left empty are not sent back, so Cognito uses its default message.
+// triggerSource: CustomMessage_SignUp, CustomMessage_AdminCreateUser, CustomMessage_ResendCode,
+// CustomMessage_ForgotPassword, CustomMessage_UpdateUserAttribute, CustomMessage_VerifyUserAttribute,
+// CustomMessage_Authentication
 type CognitoTriggerCustomMessage struct {
 	CognitoTriggerCommon
 	Request struct {
 		UserAttributes    map[string]interface{} `json:"userAttributes"`
 		CodeParameter     string                 `json:"codeParameter"`
 		UsernameParameter string                 `json:"usernameParameter"`
+		ClientMetadata    map[string]string      `json:"clientMetadata,omitempty"`
 	} `json:"request"`
 	Response struct {
-		SMSMessage   string `json:"smsMessage"`
-		EmailMessage string `json:"emailMessage"`
-		EmailSubject string `json:"emailSubject"`
+		SMSMessage   string `json:"smsMessage,omitempty"`
+		EmailMessage string `json:"emailMessage,omitempty"`
+		EmailSubject string `json:"emailSubject,omitempty"`
 	} `json:"response"`
 }
 
@@ -86,6 +94,7 @@ type CognitoTriggerPostAuthentication struct {
 	Request struct {
 		UserAttributes map[string]interface{} `json:"userAttributes"`
 		NewDeviceUsed  bool                   `json:"newDeviceUsed"`
+		ClientMetadata map[string]string      `json:"clientMetadata,omitempty"`
 	} `json:"request"`
 	Response map[string]interface{} `json:"response"`
 }
@@ -98,38 +107,217 @@ type CognitoTriggerPreAuthentication struct {
 	Request struct {
 		UserAttributes map[string]interface{} `json:"userAttributes"`
 		ValidationData map[string]interface{} `json:"validationData"`
+		UserNotFound   bool                   `json:"userNotFound,omitempty"`
 	} `json:"request"`
 	Response map[string]interface{} `json:"response"`
 }
 
-// CognitoTriggerTokenGeneration is invoked before the token generation, allowing you to customize the claims
-// in the identity token.
-// triggerSource: TokenGeneration_HostedAuth
+// CognitoTriggerTokenGeneration (pre token generation) is invoked before the token generation, allowing you to
+// customize the claims in the identity token.
+// triggerSource: TokenGeneration_HostedAuth, TokenGeneration_Authentication, TokenGeneration_NewPasswordChallenge,
+// TokenGeneration_AuthenticateDevice, TokenGeneration_RefreshTokens
 type CognitoTriggerTokenGeneration struct {
 	CognitoTriggerCommon
 	Request struct {
-		UserAttributes     map[string]interface{} `json:"userAttributes"`
-		GroupConfiguration map[string]interface{} `json:"groupConfiguration"`
+		UserAttributes     map[string]interface{}           `json:"userAttributes"`
+		GroupConfiguration CognitoTriggerGroupConfiguration `json:"groupConfiguration"`
+		ClientMetadata     map[string]string                `json:"clientMetadata,omitempty"`
 	} `json:"request"`
-	Response map[string]interface{} `json:"response"` // default is map[claimsOverrideDetails: <nil>]
+	Response struct {
+		// Left nil, the claims are not changed
+		ClaimsOverrideDetails *CognitoTriggerClaimsOverrideDetails `json:"claimsOverrideDetails"`
+	} `json:"response"`
+}
+
+// CognitoTriggerGroupConfiguration holds the groups and IAM roles of the user being issued tokens
+type CognitoTriggerGroupConfiguration struct {
+	GroupsToOverride   []string `json:"groupsToOverride"`
+	IAMRolesToOverride []string `json:"iamRolesToOverride"`
+	PreferredRole      *string  `json:"preferredRole"`
+}
+
+// CognitoTriggerClaimsOverrideDetails adds, overrides or suppresses identity token claims and can override the groups
+type CognitoTriggerClaimsOverrideDetails struct {
+	ClaimsToAddOrOverride map[string]string                 `json:"claimsToAddOrOverride,omitempty"`
+	ClaimsToSuppress      []string                          `json:"claimsToSuppress,omitempty"`
+	GroupOverrideDetails  *CognitoTriggerGroupConfiguration `json:"groupOverrideDetails,omitempty"`
+}
+
+// OverrideClaim will add or override a claim in the identity token
+func (evt *CognitoTriggerTokenGeneration) OverrideClaim(name string, value string) {
+	if evt.Response.ClaimsOverrideDetails == nil {
+		evt.Response.ClaimsOverrideDetails = &CognitoTriggerClaimsOverrideDetails{}
+	}
+	if evt.Response.ClaimsOverrideDetails.ClaimsToAddOrOverride == nil {
+		evt.Response.ClaimsOverrideDetails.ClaimsToAddOrOverride = make(map[string]string)
+	}
+	evt.Response.ClaimsOverrideDetails.ClaimsToAddOrOverride[name] = value
+}
+
+// SuppressClaim will remove a claim from the identity token
+func (evt *CognitoTriggerTokenGeneration) SuppressClaim(name string) {
+	if evt.Response.ClaimsOverrideDetails == nil {
+		evt.Response.ClaimsOverrideDetails = &CognitoTriggerClaimsOverrideDetails{}
+	}
+	evt.Response.ClaimsOverrideDetails.ClaimsToSuppress = append(evt.Response.ClaimsOverrideDetails.ClaimsToSuppress, name)
+}
+
+// CognitoTriggerChallengeResult is a challenge the user has already answered during custom authentication
+type CognitoTriggerChallengeResult struct {
+	ChallengeName     string `json:"challengeName"`
+	ChallengeResult   bool   `json:"challengeResult"`
+	ChallengeMetadata string `json:"challengeMetadata,omitempty"`
+}
+
+// CognitoTriggerDefineAuthChallenge is invoked to decide the next challenge in a custom authentication flow,
+// or whether to issue tokens or fail the authentication.
+// triggerSource: DefineAuthChallenge_Authentication
+type CognitoTriggerDefineAuthChallenge struct {
+	CognitoTriggerCommon
+	Request struct {
+		UserAttributes map[string]interface{}          `json:"userAttributes"`
+		Session        []CognitoTriggerChallengeResult `json:"session"`
+		ClientMetadata map[string]string               `json:"clientMetadata,omitempty"`
+		UserNotFound   bool                            `json:"userNotFound,omitempty"`
+	} `json:"request"`
+	Response struct {
+		ChallengeName      string `json:"challengeName,omitempty"`
+		IssueTokens        bool   `json:"issueTokens"`
+		FailAuthentication bool   `json:"failAuthentication"`
+	} `json:"response"`
+}
+
+// CognitoTriggerCreateAuthChallenge is invoked to create the challenge chosen by DefineAuthChallenge in a custom
+// authentication flow. Public parameters are sent to the client, private parameters are kept for verifying the answer.
+// triggerSource: CreateAuthChallenge_Authentication
+type CognitoTriggerCreateAuthChallenge struct {
+	CognitoTriggerCommon
+	Request struct {
+		UserAttributes map[string]interface{}          `json:"userAttributes"`
+		ChallengeName  string                          `json:"challengeName"`
+		Session        []CognitoTriggerChallengeResult `json:"session"`
+		ClientMetadata map[string]string               `json:"clientMetadata,omitempty"`
+		UserNotFound   bool                            `json:"userNotFound,omitempty"`
+	} `json:"request"`
+	Response struct {
+		PublicChallengeParameters  map[string]string `json:"publicChallengeParameters"`
+		PrivateChallengeParameters map[string]string `json:"privateChallengeParameters"`
+		ChallengeMetadata          string            `json:"challengeMetadata,omitempty"`
+	} `json:"response"`
+}
+
+// CognitoTriggerVerifyAuthChallengeResponse is invoked to verify the user's answer to a custom authentication challenge.
+// triggerSource: VerifyAuthChallengeResponse_Authentication
+type CognitoTriggerVerifyAuthChallengeResponse struct {
+	CognitoTriggerCommon
+	Request struct {
+		UserAttributes             map[string]interface{} `json:"userAttributes"`
+		PrivateChallengeParameters map[string]string      `json:"privateChallengeParameters"`
+		ChallengeAnswer            string                 `json:"challengeAnswer"`
+		ClientMetadata             map[string]string      `json:"clientMetadata,omitempty"`
+		UserNotFound               bool                   `json:"userNotFound,omitempty"`
+	} `json:"request"`
+	Response struct {
+		AnswerCorrect bool `json:"answerCorrect"`
+	} `json:"response"`
+}
+
+// CognitoTriggerUserMigration is invoked when a user isn't found in the pool, either signing in or resetting their
+// password, allowing you to migrate the user from an existing user directory.
+// triggerSource: UserMigration_Authentication, UserMigration_ForgotPassword
+type CognitoTriggerUserMigration struct {
+	CognitoTriggerCommon
+	Request struct {
+		Password       string                 `json:"password"`
+		ValidationData map[string]interface{} `json:"validationData"`
+		ClientMetadata map[string]string      `json:"clientMetadata,omitempty"`
+	} `json:"request"`
+	Response struct {
+		UserAttributes         map[string]string `json:"userAttributes"`
+		FinalUserStatus        string            `json:"finalUserStatus,omitempty"`
+		MessageAction          string            `json:"messageAction,omitempty"`
+		DesiredDeliveryMediums []string          `json:"desiredDeliveryMediums,omitempty"`
+		ForceAliasCreation     bool              `json:"forceAliasCreation"`
+	} `json:"response"`
+}
+
+// CognitoTriggerCustomSender is invoked instead of Cognito sending an SMS or email message, allowing you to send
+// it with a third party provider. The code is encrypted with the user pool's KMS key, decrypt it (ie. with the
+// AWS Encryption SDK) before sending it. Nothing needs to be set on the response.
+// triggerSource: CustomSMSSender_SignUp, CustomSMSSender_ResendCode, CustomSMSSender_ForgotPassword,
+// CustomSMSSender_UpdateUserAttribute, CustomSMSSender_VerifyUserAttribute, CustomSMSSender_AdminCreateUser,
+// CustomSMSSender_AccountTakeOverNotification, CustomSMSSender_Authentication and the same for CustomEmailSender
+// (except CustomEmailSender_Authentication)
+type CognitoTriggerCustomSender struct {
+	CognitoTriggerCommon
+	Request struct {
+		Type           string                 `json:"type"`
+		Code           string                 `json:"code"`
+		UserAttributes map[string]interface{} `json:"userAttributes"`
+		ClientMetadata map[string]string      `json:"clientMetadata,omitempty"`
+	} `json:"request"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// cognitoTriggerSources lists every trigger source for each trigger, keyed by the prefix of the trigger source
+var cognitoTriggerSources = map[string][]string{
+	"PreSignUp":        {"PreSignUp_SignUp", "PreSignUp_AdminCreateUser", "PreSignUp_ExternalProvider"},
+	"PostConfirmation": {"PostConfirmation_ConfirmSignUp", "PostConfirmation_ConfirmForgotPassword"},
+	"CustomMessage": {
+		"CustomMessage_SignUp", "CustomMessage_AdminCreateUser", "CustomMessage_ResendCode",
+		"CustomMessage_ForgotPassword", "CustomMessage_UpdateUserAttribute", "CustomMessage_VerifyUserAttribute",
+		"CustomMessage_Authentication",
+	},
+	"PostAuthentication": {"PostAuthentication_Authentication"},
+	"PreAuthentication":  {"PreAuthentication_Authentication"},
+	"TokenGeneration": {
+		"TokenGeneration_HostedAuth", "TokenGeneration_Authentication", "TokenGeneration_NewPasswordChallenge",
+		"TokenGeneration_AuthenticateDevice", "TokenGeneration_RefreshTokens",
+	},
+	"DefineAuthChallenge":         {"DefineAuthChallenge_Authentication"},
+	"CreateAuthChallenge":         {"CreateAuthChallenge_Authentication"},
+	"VerifyAuthChallengeResponse": {"VerifyAuthChallengeResponse_Authentication"},
+	"UserMigration":               {"UserMigration_Authentication", "UserMigration_ForgotPassword"},
+	"CustomSMSSender": {
+		"CustomSMSSender_SignUp", "CustomSMSSender_ResendCode", "CustomSMSSender_ForgotPassword",
+		"CustomSMSSender_UpdateUserAttribute", "CustomSMSSender_VerifyUserAttribute", "CustomSMSSender_AdminCreateUser",
+		"CustomSMSSender_AccountTakeOverNotification", "CustomSMSSender_Authentication",
+	},
+	"CustomEmailSender": {
+		"CustomEmailSender_SignUp", "CustomEmailSender_ResendCode", "CustomEmailSender_ForgotPassword",
+		"CustomEmailSender_UpdateUserAttribute", "CustomEmailSender_VerifyUserAttribute", "CustomEmailSender_AdminCreateUser",
+		"CustomEmailSender_AccountTakeOverNotification",
+	},
+}
+
+// cognitoTriggerTypes maps the prefix of a trigger source to the name of its struct
+var cognitoTriggerTypes = map[string]string{
+	"PreSignUp":                   "CognitoTriggerPreSignup",
+	"PostConfirmation":            "CognitoTriggerPostConfirmation",
+	"CustomMessage":               "CognitoTriggerCustomMessage",
+	"PostAuthentication":          "CognitoTriggerPostAuthentication",
+	"PreAuthentication":           "CognitoTriggerPreAuthentication",
+	"TokenGeneration":             "CognitoTriggerTokenGeneration",
+	"DefineAuthChallenge":         "CognitoTriggerDefineAuthChallenge",
+	"CreateAuthChallenge":         "CognitoTriggerCreateAuthChallenge",
+	"VerifyAuthChallengeResponse": "CognitoTriggerVerifyAuthChallengeResponse",
+	"UserMigration":               "CognitoTriggerUserMigration",
+	"CustomSMSSender":             "CognitoTriggerCustomSender",
+	"CustomEmailSender":           "CognitoTriggerCustomSender",
 }
 
 // GetCognitoTriggerType returns the name of the struct for the Cognito trigger
 func GetCognitoTriggerType(evt map[string]interface{}) string {
 	// triggerSource key will have the Cognito trigger type, ie. PreSignUp_SignUp
-	switch evt["triggerSource"].(string) {
-	case "PreSignUp_AdminCreateUser", "PreSignUp_SignUp":
-		return "CognitoTriggerPreSignup"
-	case "PostConfirmation_ConfirmSignUp", "PostConfirmation_ConfirmForgotPassword":
-		return "CognitoTriggerPostConfirmation"
-	case "CustomMessage_ResendCode":
-		return "CognitoTriggerCustomMessage"
-	case "PostAuthentication_Authentication":
-		return "CognitoTriggerPostAuthentication"
-	case "PreAuthentication_Authentication":
-		return "CognitoTriggerPreAuthentication"
-	case "TokenGeneration_HostedAuth":
-		return "CognitoTriggerTokenGeneration"
+	triggerSource, _ := evt["triggerSource"].(string)
+	prefix := triggerSource
+	if i := strings.Index(triggerSource, "_"); i > -1 {
+		prefix = triggerSource[:i]
+	}
+	for _, source := range cognitoTriggerSources[prefix] {
+		if source == triggerSource {
+			return cognitoTriggerTypes[prefix]
+		}
 	}
 	return ""
 }
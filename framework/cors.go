@@ -0,0 +1,128 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures CORSMiddleware
+type CORSConfig struct {
+	// AllowOrigins are the origins that can make requests, "*" allows any and patterns can use * to match any
+	// characters, ie. "https://*.example.com"
+	AllowOrigins []string
+	// AllowMethods are the methods allowed for preflight requests (default GET, HEAD, PUT, PATCH, POST and DELETE)
+	AllowMethods []string
+	// AllowHeaders are the request headers allowed for preflight requests, by default any requested are allowed
+	AllowHeaders []string
+	// AllowCredentials allows requests with credentials, such as cookies. The request's origin is returned
+	// instead of "*" when it's set, as browsers require.
+	AllowCredentials bool
+	// ExposeHeaders are response headers that browsers will let scripts read
+	ExposeHeaders []string
+	// MaxAge is the number of seconds browsers can cache the preflight response for (not set when 0)
+	MaxAge int
+}
+
+// defaultCORSMethods are the methods allowed when CORSConfig.AllowMethods isn't set
+var defaultCORSMethods = []string{get, head, put, patch, post, delete}
+
+// CORSMiddleware returns middleware that sets CORS headers for requests from allowed origins and answers preflight
+// requests. Add it with Router.Use() so preflight requests are answered before they're routed. Requests from
+// origins that aren't allowed are handled without CORS headers, so browsers will block them.
+func CORSMiddleware(cfg CORSConfig) Middleware {
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+
+	return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) bool {
+		origin := req.GetHeader(HeaderOrigin)
+		preflight := req.HTTPMethod == options && origin != "" && req.GetHeader(HeaderAccessControlRequestMethod) != ""
+		allowOrigin := cfg.allowOrigin(origin)
+		if allowOrigin != "*" {
+			// The response depends on the origin, so caches must not share it between origins
+			addVary(res, HeaderOrigin)
+		}
+		if allowOrigin == "" {
+			return true
+		}
+
+		res.SetHeader(HeaderAccessControlAllowOrigin, allowOrigin)
+		if cfg.AllowCredentials {
+			res.SetHeader(HeaderAccessControlAllowCredentials, "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				res.SetHeader(HeaderAccessControlExposeHeaders, exposeHeaders)
+			}
+			return true
+		}
+
+		addVary(res, HeaderAccessControlRequestMethod)
+		addVary(res, HeaderAccessControlRequestHeaders)
+		res.SetHeader(HeaderAccessControlAllowMethods, allowMethods)
+		if allowHeaders != "" {
+			res.SetHeader(HeaderAccessControlAllowHeaders, allowHeaders)
+		} else if requested := req.GetHeader(HeaderAccessControlRequestHeaders); requested != "" {
+			res.SetHeader(HeaderAccessControlAllowHeaders, requested)
+		}
+		if cfg.MaxAge > 0 {
+			res.SetHeader(HeaderAccessControlMaxAge, strconv.Itoa(cfg.MaxAge))
+		}
+		res.SetStatus(http.StatusNoContent)
+		return false
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a request's origin, or an empty string if
+// the origin isn't allowed
+func (cfg *CORSConfig) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range cfg.AllowOrigins {
+		if allowed == "*" {
+			if cfg.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) || (strings.Contains(allowed, "*") && matchWildcard(allowed, origin)) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// addVary adds a header name to the response's Vary header
+func addVary(res *APIGatewayProxyResponse, name string) {
+	vary := res.GetHeader(HeaderVary)
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), name) {
+			return
+		}
+	}
+	if vary != "" {
+		name = vary + ", " + name
+	}
+	res.SetHeader(HeaderVary, name)
+}
@@ -0,0 +1,128 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	handled := false
+	router := NewRouter(func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		res.StatusCode = 404
+		return nil
+	})
+	router.Use(CORSMiddleware(CORSConfig{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Total-Count"},
+		MaxAge:           600,
+	}))
+	router.GET("/widgets", func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		handled = true
+		res.String(200, "widgets")
+		return nil
+	})
+
+	request := func(method string, headers map[string]string) APIGatewayProxyResponse {
+		handled = false
+		res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{HTTPMethod: method, Path: "/widgets", Headers: headers})
+		return res
+	}
+
+	Convey("CORSMiddleware", t, func() {
+		Convey("Should set CORS headers for an allowed origin", func() {
+			res := request("GET", map[string]string{"origin": "https://example.com"})
+			So(handled, ShouldBeTrue)
+			So(res.Headers[HeaderAccessControlAllowOrigin], ShouldEqual, "https://example.com")
+			So(res.Headers[HeaderAccessControlAllowCredentials], ShouldEqual, "true")
+			So(res.Headers[HeaderAccessControlExposeHeaders], ShouldEqual, "X-Total-Count")
+			So(res.Headers[HeaderVary], ShouldEqual, HeaderOrigin)
+			So(res.Headers, ShouldNotContainKey, HeaderAccessControlAllowMethods)
+		})
+
+		Convey("Should match origin patterns", func() {
+			res := request("GET", map[string]string{"Origin": "https://api.example.org"})
+			So(res.Headers[HeaderAccessControlAllowOrigin], ShouldEqual, "https://api.example.org")
+		})
+
+		Convey("Should not set CORS headers for other origins", func() {
+			res := request("GET", map[string]string{"Origin": "https://example.net"})
+			So(handled, ShouldBeTrue)
+			So(res.Headers, ShouldNotContainKey, HeaderAccessControlAllowOrigin)
+
+			res = request("GET", nil)
+			So(handled, ShouldBeTrue)
+			So(res.Headers, ShouldNotContainKey, HeaderAccessControlAllowOrigin)
+		})
+
+		Convey("Should answer preflight requests without routing them", func() {
+			res := request("OPTIONS", map[string]string{"Origin": "https://example.com", HeaderAccessControlRequestMethod: "PUT"})
+			So(handled, ShouldBeFalse)
+			So(res.StatusCode, ShouldEqual, 204)
+			So(res.Headers[HeaderAccessControlAllowOrigin], ShouldEqual, "https://example.com")
+			So(res.Headers[HeaderAccessControlAllowMethods], ShouldEqual, "GET, HEAD, PUT, PATCH, POST, DELETE")
+			So(res.Headers[HeaderAccessControlAllowHeaders], ShouldEqual, "Content-Type, Authorization")
+			So(res.Headers[HeaderAccessControlMaxAge], ShouldEqual, "600")
+			So(res.Headers[HeaderVary], ShouldEqual, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+		})
+
+		Convey("Should leave preflight requests from other origins to the router", func() {
+			res := request("OPTIONS", map[string]string{"Origin": "https://example.net", HeaderAccessControlRequestMethod: "PUT"})
+			So(res.StatusCode, ShouldEqual, 204)
+			So(res.Headers[HeaderAllow], ShouldEqual, "GET, HEAD, OPTIONS")
+			So(res.Headers, ShouldNotContainKey, HeaderAccessControlAllowOrigin)
+		})
+
+		Convey("Should use the same middleware with the local gateway", func() {
+			r := httptest.NewRequest("OPTIONS", "/widgets", nil)
+			r.Header.Set(HeaderOrigin, "https://example.com")
+			r.Header.Set(HeaderAccessControlRequestMethod, "POST")
+			rw := httptest.NewRecorder()
+			gatewayHandler(*router).ServeHTTP(rw, r)
+			So(rw.Code, ShouldEqual, 204)
+			So(rw.Header().Get(HeaderAccessControlAllowOrigin), ShouldEqual, "https://example.com")
+			So(rw.Header().Get(HeaderAccessControlMaxAge), ShouldEqual, "600")
+		})
+	})
+
+	Convey("allowOrigin", t, func() {
+		Convey("Should allow any origin with *", func() {
+			cfg := CORSConfig{AllowOrigins: []string{"*"}}
+			So(cfg.allowOrigin("https://example.com"), ShouldEqual, "*")
+			So(cfg.allowOrigin(""), ShouldEqual, "")
+		})
+
+		Convey("Should return the origin for * when credentials are allowed", func() {
+			cfg := CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}
+			So(cfg.allowOrigin("https://example.com"), ShouldEqual, "https://example.com")
+		})
+
+		Convey("Should echo the requested headers when none are configured", func() {
+			res := APIGatewayProxyResponse{}
+			req := APIGatewayProxyRequest{HTTPMethod: "OPTIONS", Headers: map[string]string{HeaderOrigin: "https://example.com", HeaderAccessControlRequestMethod: "GET", HeaderAccessControlRequestHeaders: "X-Custom"}}
+			ok := CORSMiddleware(CORSConfig{AllowOrigins: []string{"*"}})(context.Background(), &HandlerDependencies{}, &req, &res, url.Values{})
+			So(ok, ShouldBeFalse)
+			So(res.Headers[HeaderAccessControlAllowOrigin], ShouldEqual, "*")
+			So(res.Headers[HeaderAccessControlAllowHeaders], ShouldEqual, "X-Custom")
+		})
+	})
+}
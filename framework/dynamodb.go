@@ -0,0 +1,213 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/gobwas/glob"
+)
+
+// These types are not aliased from aws-lambda-go's DynamoDBEvent on purpose. Holding the images as AWS SDK
+// attribute values means they can be unmarshalled with the dynamodbattribute package, just like items read
+// from a table, so the same structs (and `dynamodbav` tags) work for both.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-ddb.html
+
+// DynamoDBStreamEvent is a batch of records from a DynamoDB stream
+type DynamoDBStreamEvent struct {
+	Records []DynamoDBStreamRecord `json:"Records"`
+}
+
+// DynamoDBStreamRecord is a single change (record) from a DynamoDBStreamEvent
+type DynamoDBStreamRecord struct {
+	AWSRegion      string               `json:"awsRegion"`
+	EventID        string               `json:"eventID"`
+	EventName      string               `json:"eventName"`
+	EventSource    string               `json:"eventSource"`
+	EventSourceARN string               `json:"eventSourceARN"`
+	EventVersion   string               `json:"eventVersion"`
+	DynamoDB       DynamoDBStreamChange `json:"dynamodb"`
+}
+
+// DynamoDBStreamChange holds the keys and item images for a DynamoDBStreamRecord
+type DynamoDBStreamChange struct {
+	ApproximateCreationDateTime float64                             `json:"ApproximateCreationDateTime"`
+	Keys                        map[string]*dynamodb.AttributeValue `json:"Keys"`
+	NewImage                    map[string]*dynamodb.AttributeValue `json:"NewImage"`
+	OldImage                    map[string]*dynamodb.AttributeValue `json:"OldImage"`
+	SequenceNumber              string                              `json:"SequenceNumber"`
+	SizeBytes                   int64                               `json:"SizeBytes"`
+	StreamViewType              string                              `json:"StreamViewType"`
+}
+
+// DynamoDBEventResponse is returned to Lambda so that processing resumes from the first failed record.
+// The event source mapping must have the ReportBatchItemFailures function response type enabled.
+type DynamoDBEventResponse struct {
+	BatchItemFailures []DynamoDBBatchItemFailure `json:"batchItemFailures"`
+}
+
+// DynamoDBBatchItemFailure identifies a record (by sequence number) that could not be processed
+type DynamoDBBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// DynamoDB stream event names
+const (
+	DynamoDBInsert = "INSERT"
+	DynamoDBModify = "MODIFY"
+	DynamoDBRemove = "REMOVE"
+)
+
+// DynamoDBStreamRouter struct provides an interface to handle DynamoDB stream records (routed by table ARN and event name)
+type DynamoDBStreamRouter struct {
+	routes      []dynamoDBRoute
+	rootHandler DynamoDBStreamHandler
+	Tracer      TraceStrategy
+}
+
+// DynamoDBStreamHandler handles a single routed DynamoDB stream record
+type DynamoDBStreamHandler func(context.Context, *HandlerDependencies, *DynamoDBStreamRecord) error
+
+// dynamoDBRoute holds a handler along with the table ARN glob and event name it matches (empty event name matches all)
+type dynamoDBRoute struct {
+	matcher   glob.Glob
+	eventName string
+	handler   DynamoDBStreamHandler
+}
+
+// LambdaHandler handles DynamoDB stream events. Records are processed in order and processing stops at the first
+// failure, which is reported back to Lambda so the batch is retried from that record on.
+func (r *DynamoDBStreamRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt DynamoDBStreamEvent) (DynamoDBEventResponse, error) {
+	response := DynamoDBEventResponse{BatchItemFailures: []DynamoDBBatchItemFailure{}}
+
+	for i := range evt.Records {
+		record := &evt.Records[i]
+
+		handler, ok := r.handlerFor(record)
+		r.Tracer.Annotations = map[string]interface{}{
+			"DynamoDBTableArn":       record.TableARN(),
+			"DynamoDBEvent":          record.EventName,
+			"DynamoDBSequenceNumber": record.DynamoDB.SequenceNumber,
+		}
+		if !ok {
+			log.Println("using default fall through handler")
+			r.Tracer.Annotations["FallthroughHandler"] = true
+		}
+
+		err := r.Tracer.Capture(ctx, "DynamoDBStreamHandler", func(ctx1 context.Context) error {
+			r.Tracer.AddAnnotations(ctx1)
+			r.Tracer.AddMetadata(ctx1)
+			d.Tracer = &r.Tracer
+			return handler(ctx1, d, record)
+		})
+		if err != nil {
+			log.Println("could not process DynamoDB stream record", record.DynamoDB.SequenceNumber, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, DynamoDBBatchItemFailure{ItemIdentifier: record.DynamoDB.SequenceNumber})
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// handlerFor returns the first registered handler matching the record's table ARN and event name,
+// or the fall through handler (with false) when nothing matched.
+func (r *DynamoDBStreamRouter) handlerFor(record *DynamoDBStreamRecord) (DynamoDBStreamHandler, bool) {
+	tableArn := record.TableARN()
+	for _, route := range r.routes {
+		if (route.eventName == "" || route.eventName == record.EventName) && route.matcher.Match(tableArn) {
+			return route.handler, true
+		}
+	}
+
+	// It's possible that the DynamoDBStreamRouter wasn't created with NewDynamoDBStreamRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *DynamoDBStreamRecord) error { return nil }, false
+	}
+	return r.rootHandler, false
+}
+
+// TableARN returns the ARN of the table from the record's stream ARN
+// ie. arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2018-04-10T00:00:00.000 -> arn:aws:dynamodb:us-east-1:123456789012:table/orders
+func (record *DynamoDBStreamRecord) TableARN() string {
+	if i := strings.Index(record.EventSourceARN, "/stream/"); i > -1 {
+		return record.EventSourceARN[:i]
+	}
+	return record.EventSourceARN
+}
+
+// UnmarshalNewImage will unmarshal the item as it appeared after it was modified (INSERT and MODIFY events)
+func (record *DynamoDBStreamRecord) UnmarshalNewImage(out interface{}) error {
+	return dynamodbattribute.UnmarshalMap(record.DynamoDB.NewImage, out)
+}
+
+// UnmarshalOldImage will unmarshal the item as it appeared before it was modified (MODIFY and REMOVE events)
+func (record *DynamoDBStreamRecord) UnmarshalOldImage(out interface{}) error {
+	return dynamodbattribute.UnmarshalMap(record.DynamoDB.OldImage, out)
+}
+
+// UnmarshalKeys will unmarshal the primary key attribute(s) of the modified item
+func (record *DynamoDBStreamRecord) UnmarshalKeys(out interface{}) error {
+	return dynamodbattribute.UnmarshalMap(record.DynamoDB.Keys, out)
+}
+
+// Listen will start a DynamoDB stream listener that handles incoming records
+func (r *DynamoDBStreamRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewDynamoDBStreamRouter simply returns a new DynamoDBStreamRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewDynamoDBStreamRouter(rootHandler ...DynamoDBStreamHandler) *DynamoDBStreamRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and the record is considered handled.
+	handler := func(context.Context, *HandlerDependencies, *DynamoDBStreamRecord) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &DynamoDBStreamRouter{
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for records from tables whose ARN matches the given glob and the given
+// event name (INSERT, MODIFY or REMOVE). An empty event name handles all events.
+func (r *DynamoDBStreamRouter) Handle(tableArnMatch string, eventName string, handler DynamoDBStreamHandler) {
+	r.routes = append(r.routes, dynamoDBRoute{
+		matcher:   glob.MustCompile(tableArnMatch),
+		eventName: eventName,
+		handler:   handler,
+	})
+}
+
+// Insert is the same as Handle only the event is already implied.
+func (r *DynamoDBStreamRouter) Insert(tableArnMatch string, handler DynamoDBStreamHandler) {
+	r.Handle(tableArnMatch, DynamoDBInsert, handler)
+}
+
+// Modify is the same as Handle only the event is already implied.
+func (r *DynamoDBStreamRouter) Modify(tableArnMatch string, handler DynamoDBStreamHandler) {
+	r.Handle(tableArnMatch, DynamoDBModify, handler)
+}
+
+// Remove is the same as Handle only the event is already implied.
+func (r *DynamoDBStreamRouter) Remove(tableArnMatch string, handler DynamoDBStreamHandler) {
+	r.Handle(tableArnMatch, DynamoDBRemove, handler)
+}
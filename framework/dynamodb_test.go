@@ -0,0 +1,133 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDynamoDBStreamRouter(t *testing.T) {
+	testHandler := func(ctx context.Context, d *HandlerDependencies, record *DynamoDBStreamRecord) error {
+		return nil
+	}
+
+	testRouter := NewDynamoDBStreamRouter()
+	Convey("NewDynamoDBStreamRouter", t, func() {
+		Convey("Should create a new DynamoDBStreamRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Insert("arn:aws:dynamodb:*:table/orders", testHandler)
+
+	testEvt := `{"Records":[{
+		"eventID":"1",
+		"eventName":"INSERT",
+		"eventSource":"aws:dynamodb",
+		"eventSourceARN":"arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2018-04-10T00:00:00.000",
+		"dynamodb":{
+			"Keys":{"id":{"S":"abc"}},
+			"NewImage":{"id":{"S":"abc"},"total":{"N":"42"}},
+			"SequenceNumber":"111",
+			"StreamViewType":"NEW_AND_OLD_IMAGES"
+		}
+	}]}`
+	var evtMap map[string]interface{}
+	json.Unmarshal([]byte(testEvt), &evtMap)
+	var evt DynamoDBStreamEvent
+	decodeErr := decodeJSONEvent(evtMap, &evt)
+
+	Convey("Should decode a DynamoDBStreamEvent", t, func() {
+		So(getType(evtMap), ShouldEqual, "DynamoDBStreamEvent")
+		So(decodeErr, ShouldBeNil)
+		So(evt.Records, ShouldHaveLength, 1)
+		So(evt.Records[0].DynamoDB.SequenceNumber, ShouldEqual, "111")
+	})
+
+	Convey("TableARN", t, func() {
+		Convey("Should strip the stream from the event source ARN", func() {
+			So(evt.Records[0].TableARN(), ShouldEqual, "arn:aws:dynamodb:us-east-1:123456789012:table/orders")
+		})
+	})
+
+	Convey("UnmarshalNewImage", t, func() {
+		Convey("Should unmarshal the new image into a struct", func() {
+			var order struct {
+				ID    string `json:"id"`
+				Total int    `json:"total"`
+			}
+			err := evt.Records[0].UnmarshalNewImage(&order)
+			So(err, ShouldBeNil)
+			So(order.ID, ShouldEqual, "abc")
+			So(order.Total, ShouldEqual, 42)
+		})
+	})
+
+	Convey("handlerFor", t, func() {
+		Convey("Should match on table ARN and event name", func() {
+			_, ok := testRouter.handlerFor(&evt.Records[0])
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Should fall through for other event names", func() {
+			record := evt.Records[0]
+			record.EventName = DynamoDBRemove
+			_, ok := testRouter.handlerFor(&record)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		handled := []string{}
+		failingRouter := NewDynamoDBStreamRouter(func(ctx context.Context, d *HandlerDependencies, record *DynamoDBStreamRecord) error {
+			handled = append(handled, record.DynamoDB.SequenceNumber)
+			if record.EventName == DynamoDBRemove {
+				return errors.New("could not process")
+			}
+			return nil
+		})
+		batch := func(eventNames ...string) DynamoDBStreamEvent {
+			handled = []string{}
+			evt := DynamoDBStreamEvent{}
+			for i, name := range eventNames {
+				record := DynamoDBStreamRecord{EventName: name}
+				record.DynamoDB.SequenceNumber = strconv.Itoa(i + 1)
+				evt.Records = append(evt.Records, record)
+			}
+			return evt
+		}
+
+		Convey("Should report the first failed record and stop processing", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, batch(DynamoDBInsert, DynamoDBRemove, DynamoDBInsert))
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldResemble, []DynamoDBBatchItemFailure{{ItemIdentifier: "2"}})
+			So(handled, ShouldResemble, []string{"1", "2"})
+		})
+
+		Convey("Should return an empty list when every record was processed", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, batch(DynamoDBInsert, DynamoDBModify))
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldNotBeNil)
+			So(res.BatchItemFailures, ShouldBeEmpty)
+			So(handled, ShouldResemble, []string{"1", "2"})
+		})
+	})
+}
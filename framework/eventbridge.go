@@ -0,0 +1,345 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// EventBridgeRouter struct provides an interface to handle EventBridge (CloudWatch Events) events matched by event pattern
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+type EventBridgeRouter struct {
+	routes      []eventBridgeRoute
+	rootHandler EventBridgeHandler
+	Tracer      TraceStrategy
+}
+
+// EventBridgeHandler handles a routed EventBridge event
+type EventBridgeHandler func(context.Context, *HandlerDependencies, *CloudWatchEvent) error
+
+// EventPattern is an EventBridge event pattern, ie. {"source": ["aws.s3"], "detail": {"bucket": {"name": [{"prefix": "aegis-"}]}}}
+type EventPattern map[string]interface{}
+
+// eventBridgeRoute holds a handler along with the event pattern it matches
+type eventBridgeRoute struct {
+	pattern map[string]interface{}
+	handler EventBridgeHandler
+}
+
+// LambdaHandler handles EventBridge events.
+func (r *EventBridgeRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt CloudWatchEvent) error {
+	handler, ok := r.handlerFor(&evt)
+	r.Tracer.Annotations = map[string]interface{}{
+		"EventBridgeSource":     evt.Source,
+		"EventBridgeDetailType": evt.DetailType,
+		"EventBridgeEventID":    evt.ID,
+	}
+	if !ok {
+		log.Println("using default fall through handler")
+		r.Tracer.Annotations["FallthroughHandler"] = true
+	}
+
+	return r.Tracer.Capture(ctx, "EventBridgeHandler", func(ctx1 context.Context) error {
+		r.Tracer.AddAnnotations(ctx1)
+		r.Tracer.AddMetadata(ctx1)
+		d.Tracer = &r.Tracer
+		return handler(ctx1, d, &evt)
+	})
+}
+
+// handlerFor returns the handler for the first registered pattern the event matches,
+// or the fall through handler (with false) when nothing matched.
+func (r *EventBridgeRouter) handlerFor(evt *CloudWatchEvent) (EventBridgeHandler, bool) {
+	if len(r.routes) > 0 {
+		// Patterns are matched against the event as JSON, just like EventBridge does
+		e := *evt
+		if len(e.Detail) == 0 {
+			e.Detail = json.RawMessage("{}")
+		}
+		var m map[string]interface{}
+		if b, err := json.Marshal(e); err == nil {
+			json.Unmarshal(b, &m)
+		}
+		for _, route := range r.routes {
+			if matchEventPattern(route.pattern, m) {
+				return route.handler, true
+			}
+		}
+	}
+
+	// It's possible that the EventBridgeRouter wasn't created with NewEventBridgeRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *CloudWatchEvent) error { return nil }, false
+	}
+	return r.rootHandler, false
+}
+
+// UnmarshalDetail will unmarshal the event's detail into the given value (typically a pointer to a struct)
+func (evt *CloudWatchEvent) UnmarshalDetail(v interface{}) error {
+	return json.Unmarshal(evt.Detail, v)
+}
+
+// Listen will start an EventBridge listener that handles incoming events
+func (r *EventBridgeRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewEventBridgeRouter simply returns a new EventBridgeRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewEventBridgeRouter(rootHandler ...EventBridgeHandler) *EventBridgeRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and it returns nothing.
+	handler := func(context.Context, *HandlerDependencies, *CloudWatchEvent) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &EventBridgeRouter{
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for events matching the given event pattern. Patterns are checked in the order
+// they were registered. The pattern must be able to be marshaled to JSON.
+func (r *EventBridgeRouter) Handle(pattern EventPattern, handler EventBridgeHandler) {
+	// Normalize the pattern by way of JSON so that []string, int, etc. compare the same as the event values
+	var normalized map[string]interface{}
+	b, err := json.Marshal(pattern)
+	if err == nil {
+		err = json.Unmarshal(b, &normalized)
+	}
+	if err != nil {
+		panic("invalid event pattern: " + err.Error())
+	}
+	r.routes = append(r.routes, eventBridgeRoute{
+		pattern: normalized,
+		handler: handler,
+	})
+}
+
+// HandleEvent will register a handler for events from the given source and (optional) detail type
+func (r *EventBridgeRouter) HandleEvent(source string, detailType string, handler EventBridgeHandler) {
+	pattern := EventPattern{"source": []string{source}}
+	if detailType != "" {
+		pattern["detail-type"] = []string{detailType}
+	}
+	r.Handle(pattern, handler)
+}
+
+// matchEventPattern returns whether or not an event (or part of one) matches the given pattern.
+// Every field in the pattern must match. Fields hold either a nested pattern or an array of values to match.
+func matchEventPattern(pattern map[string]interface{}, evt map[string]interface{}) bool {
+	for k, p := range pattern {
+		if k == "$or" {
+			if !matchAnyEventPattern(p, evt) {
+				return false
+			}
+			continue
+		}
+
+		value, exists := evt[k]
+		switch pv := p.(type) {
+		case map[string]interface{}:
+			// A missing (or non object) field still matches a nested pattern that only has fields which may be missing,
+			// ie. {"detail": {"x": [{"exists": false}]}}
+			m, _ := value.(map[string]interface{})
+			if m == nil {
+				m = map[string]interface{}{}
+			}
+			if !matchEventPattern(pv, m) {
+				return false
+			}
+		case []interface{}:
+			if !matchEventValues(pv, value, exists) {
+				return false
+			}
+		default:
+			// Not a valid pattern, EventBridge would not have accepted it
+			return false
+		}
+	}
+	return true
+}
+
+// matchAnyEventPattern returns whether or not an event matches any of the patterns in an $or array
+func matchAnyEventPattern(patterns interface{}, evt map[string]interface{}) bool {
+	alternatives, _ := patterns.([]interface{})
+	for _, alternative := range alternatives {
+		if p, ok := alternative.(map[string]interface{}); ok && matchEventPattern(p, evt) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEventValues returns whether or not a field's value matches any of the values in the pattern.
+// If the event's value is an array, any of its values can match.
+func matchEventValues(patterns []interface{}, value interface{}, exists bool) bool {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, p := range patterns {
+		if filter, ok := p.(map[string]interface{}); ok {
+			if wantExists, ok := filter["exists"].(bool); ok {
+				if wantExists == exists {
+					return true
+				}
+				continue
+			}
+		}
+		if !exists {
+			continue
+		}
+		for _, v := range values {
+			if matchEventValue(p, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchEventValue returns whether or not a single value matches an exact value or a content filter
+func matchEventValue(p interface{}, value interface{}) bool {
+	if filter, ok := p.(map[string]interface{}); ok {
+		return matchContentFilter(filter, value)
+	}
+	return eventValueEqual(p, value)
+}
+
+// eventValueEqual compares JSON values exactly. Only strings, numbers, booleans and null can be matched exactly.
+func eventValueEqual(a interface{}, b interface{}) bool {
+	switch a.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return a == b
+}
+
+// matchContentFilter returns whether or not a value matches a content filter such as {"prefix": "aws."}
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns-content-based-filtering.html
+func matchContentFilter(filter map[string]interface{}, value interface{}) bool {
+	s, isString := value.(string)
+	for op, arg := range filter {
+		switch op {
+		case "prefix":
+			if a, ok := arg.(string); !ok || !isString || !strings.HasPrefix(s, a) {
+				return false
+			}
+		case "suffix":
+			if a, ok := arg.(string); !ok || !isString || !strings.HasSuffix(s, a) {
+				return false
+			}
+		case "equals-ignore-case":
+			if a, ok := arg.(string); !ok || !isString || !strings.EqualFold(s, a) {
+				return false
+			}
+		case "wildcard":
+			if a, ok := arg.(string); !ok || !isString || !matchWildcard(a, s) {
+				return false
+			}
+		case "anything-but":
+			if matchAnythingBut(arg, value) {
+				return false
+			}
+		case "numeric":
+			if !matchNumeric(arg, value) {
+				return false
+			}
+		case "cidr":
+			a, _ := arg.(string)
+			_, network, err := net.ParseCIDR(a)
+			ip := net.ParseIP(s)
+			if err != nil || ip == nil || !network.Contains(ip) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchAnythingBut returns whether or not the value matches what an anything-but filter excludes
+func matchAnythingBut(arg interface{}, value interface{}) bool {
+	switch a := arg.(type) {
+	case []interface{}:
+		for _, excluded := range a {
+			if eventValueEqual(excluded, value) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		// ie. {"anything-but": {"prefix": "aws."}}
+		return matchContentFilter(a, value)
+	default:
+		return eventValueEqual(a, value)
+	}
+}
+
+// matchNumeric returns whether or not a number satisfies all comparisons of a numeric filter, ie. [">", 0, "<=", 5]
+func matchNumeric(arg interface{}, value interface{}) bool {
+	n, ok := value.(float64)
+	comparisons, _ := arg.([]interface{})
+	if !ok || len(comparisons) == 0 || len(comparisons)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(comparisons); i += 2 {
+		op, _ := comparisons[i].(string)
+		operand, ok := comparisons[i+1].(float64)
+		if !ok {
+			return false
+		}
+		var matched bool
+		switch op {
+		case "=":
+			matched = n == operand
+		case "<":
+			matched = n < operand
+		case "<=":
+			matched = n <= operand
+		case ">":
+			matched = n > operand
+		case ">=":
+			matched = n >= operand
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWildcard returns whether or not a string matches a wildcard filter, where * matches any number of characters
+func matchWildcard(pattern string, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	matched, err := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", s)
+	return err == nil && matched
+}
@@ -0,0 +1,152 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventBridgeRouter(t *testing.T) {
+	routeHandled := ""
+	newHandler := func(name string) EventBridgeHandler {
+		return func(ctx context.Context, d *HandlerDependencies, evt *CloudWatchEvent) error {
+			routeHandled = name
+			return nil
+		}
+	}
+
+	testRouter := NewEventBridgeRouter()
+	Convey("NewEventBridgeRouter", t, func() {
+		Convey("Should create a new EventBridgeRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Handle(EventPattern{
+		"source":      []string{"aws.s3"},
+		"detail-type": []string{"Object Created"},
+		"detail": map[string]interface{}{
+			"bucket": map[string]interface{}{"name": []interface{}{map[string]string{"prefix": "aegis-"}}},
+		},
+	}, newHandler("s3"))
+	testRouter.HandleEvent("com.example.orders", "", newHandler("orders"))
+
+	Convey("handlerFor", t, func() {
+		Convey("Should match source, detail type and detail", func() {
+			evt := CloudWatchEvent{Source: "aws.s3", DetailType: "Object Created", Detail: json.RawMessage(`{"bucket":{"name":"aegis-incoming"}}`)}
+			handler, ok := testRouter.handlerFor(&evt)
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &evt)
+			So(routeHandled, ShouldEqual, "s3")
+		})
+
+		Convey("Should not match when a detail field does not match", func() {
+			evt := CloudWatchEvent{Source: "aws.s3", DetailType: "Object Created", Detail: json.RawMessage(`{"bucket":{"name":"other"}}`)}
+			_, ok := testRouter.handlerFor(&evt)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should match by source alone", func() {
+			evt := CloudWatchEvent{Source: "com.example.orders", DetailType: "Order Placed"}
+			handler, ok := testRouter.handlerFor(&evt)
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &evt)
+			So(routeHandled, ShouldEqual, "orders")
+		})
+	})
+
+	Convey("matchEventPattern", t, func() {
+		evt := map[string]interface{}{}
+		json.Unmarshal([]byte(`{
+			"source": "com.example.orders",
+			"resources": ["arn:aws:s3:::a", "arn:aws:s3:::b"],
+			"detail": {"state": "shipped", "total": 42.5, "ip": "10.0.0.12", "file": "image.PNG", "coupon": null}
+		}`), &evt)
+		match := func(pattern string) bool {
+			var p map[string]interface{}
+			json.Unmarshal([]byte(pattern), &p)
+			return matchEventPattern(p, evt)
+		}
+
+		Convey("Should match any value in an array", func() {
+			So(match(`{"detail": {"state": ["pending", "shipped"]}}`), ShouldBeTrue)
+			So(match(`{"resources": ["arn:aws:s3:::b"]}`), ShouldBeTrue)
+			So(match(`{"detail": {"state": ["pending"]}}`), ShouldBeFalse)
+		})
+
+		Convey("Should match null", func() {
+			So(match(`{"detail": {"coupon": [null]}}`), ShouldBeTrue)
+		})
+
+		Convey("Should support prefix, suffix, wildcard and equals-ignore-case", func() {
+			So(match(`{"source": [{"prefix": "com.example"}]}`), ShouldBeTrue)
+			So(match(`{"detail": {"file": [{"suffix": ".PNG"}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"file": [{"wildcard": "image.*"}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"file": [{"equals-ignore-case": "IMAGE.png"}]}}`), ShouldBeTrue)
+		})
+
+		Convey("Should support anything-but", func() {
+			So(match(`{"detail": {"state": [{"anything-but": ["cancelled", "refunded"]}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"state": [{"anything-but": "shipped"}]}}`), ShouldBeFalse)
+			So(match(`{"detail": {"state": [{"anything-but": {"prefix": "ship"}}]}}`), ShouldBeFalse)
+			So(match(`{"detail": {"missing": [{"anything-but": "shipped"}]}}`), ShouldBeFalse)
+		})
+
+		Convey("Should support numeric comparisons", func() {
+			So(match(`{"detail": {"total": [{"numeric": [">", 0, "<=", 50]}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"total": [{"numeric": [">=", 100]}]}}`), ShouldBeFalse)
+		})
+
+		Convey("Should support exists and cidr", func() {
+			So(match(`{"detail": {"state": [{"exists": true}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"missing": [{"exists": false}]}}`), ShouldBeTrue)
+			So(match(`{"detail": {"state": [{"exists": false}]}}`), ShouldBeFalse)
+			So(match(`{"detail": {"ip": [{"cidr": "10.0.0.0/24"}]}}`), ShouldBeTrue)
+		})
+
+		Convey("Should match nested patterns against missing fields like EventBridge", func() {
+			So(match(`{"missing": {"x": [{"exists": false}]}}`), ShouldBeTrue)
+			So(match(`{"missing": {"x": [{"exists": true}]}}`), ShouldBeFalse)
+			So(match(`{"missing": {"x": ["a"]}}`), ShouldBeFalse)
+		})
+
+		Convey("Should support $or", func() {
+			So(match(`{"$or": [{"source": ["aws.s3"]}, {"detail": {"state": ["shipped"]}}]}`), ShouldBeTrue)
+			So(match(`{"$or": [{"source": ["aws.s3"]}, {"detail": {"state": ["pending"]}}]}`), ShouldBeFalse)
+		})
+	})
+
+	Convey("UnmarshalDetail", t, func() {
+		Convey("Should decode the detail into a struct", func() {
+			evt := CloudWatchEvent{Detail: json.RawMessage(`{"state":"shipped"}`)}
+			var detail struct {
+				State string `json:"state"`
+			}
+			So(evt.UnmarshalDetail(&detail), ShouldBeNil)
+			So(detail.State, ShouldEqual, "shipped")
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify a CloudWatchEvent", func() {
+			evt := map[string]interface{}{"source": "aws.s3", "detail-type": "Object Created", "detail": map[string]interface{}{}}
+			So(getType(evt), ShouldEqual, "CloudWatchEvent")
+		})
+	})
+}
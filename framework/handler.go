@@ -16,6 +16,7 @@ package framework
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"time"
@@ -27,12 +28,19 @@ import (
 
 // Handlers defines a set of Aegis framework Lambda handlers
 type Handlers struct {
-	Router         *Router
-	Tasker         *Tasker
-	RPCRouter      *RPCRouter
-	S3ObjectRouter *S3ObjectRouter
-	CognitoRouter  *CognitoRouter
-	DefaultHandler DefaultHandler
+	Router               *Router
+	Tasker               *Tasker
+	RPCRouter            *RPCRouter
+	S3ObjectRouter       *S3ObjectRouter
+	SQSRouter            *SQSRouter
+	SNSRouter            *SNSRouter
+	DynamoDBStreamRouter *DynamoDBStreamRouter
+	KinesisRouter        *KinesisRouter
+	WebSocketRouter      *WebSocketRouter
+	EventBridgeRouter    *EventBridgeRouter
+	CognitoRouter        *CognitoRouter
+	CognitoSyncRouter    *CognitoSyncRouter
+	DefaultHandler       DefaultHandler
 }
 
 // HandlerDependencies defines dependencies to be injected into each handler
@@ -47,18 +55,49 @@ type DefaultHandler func(context.Context, *HandlerDependencies, *map[string]inte
 
 // getType will determine which type of event is being sent
 func getType(evt map[string]interface{}) string {
+	// if ALBTargetGroupRequest (these also have an HTTP method and path, so check first)
+	if requestContext, ok := evt["requestContext"].(map[string]interface{}); ok && keyInMap("elb", requestContext) {
+		return "ALBTargetGroupRequest"
+	}
+
 	// if APIGatewayProxyRequest
 	if keyInMap("httpMethod", evt) && keyInMap("path", evt) {
 		return "APIGatewayProxyRequest"
 	}
 
-	// if S3Event
+	// if WebSocketRequest (these have a route key and connection, but no HTTP method or path)
+	if requestContext, ok := evt["requestContext"].(map[string]interface{}); ok {
+		if keyInMap("routeKey", requestContext) && keyInMap("connectionId", requestContext) {
+			return "WebSocketRequest"
+		}
+	}
+
+	// if APIGatewayV2HTTPRequest (HTTP APIs using payload format version 2.0 and Lambda Function URLs)
+	if version, ok := evt["version"].(string); ok && version == "2.0" && keyInMap("rawPath", evt) {
+		return "APIGatewayV2HTTPRequest"
+	}
+
+	// if S3Event or one of the other "Records" based events (SQS, SNS, DynamoDB streams and Kinesis)
 	if keyInMap("Records", evt) {
-		records := evt["Records"].([]interface{})
+		records, _ := evt["Records"].([]interface{})
 		if len(records) > 0 {
-			if keyInMap("s3", records[0].(map[string]interface{})) {
+			record, _ := records[0].(map[string]interface{})
+			if keyInMap("s3", record) {
 				return "S3Event"
 			}
+			// SNS capitalizes the key, the others do not
+			if source, ok := record["EventSource"].(string); ok && source == "aws:sns" {
+				return "SNSEvent"
+			}
+			source, _ := record["eventSource"].(string)
+			switch source {
+			case "aws:sqs":
+				return "SQSEvent"
+			case "aws:dynamodb":
+				return "DynamoDBStreamEvent"
+			case "aws:kinesis":
+				return "KinesisEvent"
+			}
 		}
 	}
 
@@ -72,6 +111,11 @@ func getType(evt map[string]interface{}) string {
 		return "AegisRPC"
 	}
 
+	// if CloudWatchEvent (EventBridge events that are not Aegis tasks, tasks are sent with their own input)
+	if keyInMap("detail-type", evt) && keyInMap("source", evt) {
+		return "CloudWatchEvent"
+	}
+
 	// if Cognito trigger
 	if keyInMap("userPoolId", evt) && keyInMap("triggerSource", evt) {
 		return "CognitoTrigger"
@@ -93,6 +137,40 @@ func keyInMap(k string, m map[string]interface{}) bool {
 	return false
 }
 
+// configured returns whether or not a router was set to handle the event type
+func (h *Handlers) configured(evtType string) bool {
+	switch evtType {
+	case "SQSEvent":
+		return h.SQSRouter != nil
+	case "SNSEvent":
+		return h.SNSRouter != nil
+	case "DynamoDBStreamEvent":
+		return h.DynamoDBStreamRouter != nil
+	case "KinesisEvent":
+		return h.KinesisRouter != nil
+	case "WebSocketRequest":
+		return h.WebSocketRouter != nil
+	case "APIGatewayV2HTTPRequest", "ALBTargetGroupRequest":
+		return h.Router != nil
+	case "CloudWatchEvent":
+		// Includes scheduled events ("detail-type": "Scheduled Event"), which apps have long handled with the DefaultHandler
+		return h.EventBridgeRouter != nil
+	case "CognitoEvent":
+		return h.CognitoSyncRouter != nil
+	}
+	return true
+}
+
+// decodeJSONEvent will decode an event map into the given struct by way of JSON.
+// Unlike mapstructure, this honors json struct tags and decodes base64 strings into []byte fields.
+func decodeJSONEvent(evt map[string]interface{}, v interface{}) error {
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
 // eventHandler is a general handler that accepts an interface and determines which hanlder to use based on the event.
 // See: https://godoc.org/github.com/aws/aws-lambda-go/lambda#Start
 func (h *Handlers) eventHandler(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (interface{}, error) {
@@ -103,6 +181,10 @@ func (h *Handlers) eventHandler(ctx context.Context, d *HandlerDependencies, evt
 	// But we do need to look at the signature to make a determination.
 	evtType := getType(evt)
 	log.Println("Incoming Lambda event type: ", evtType)
+	// Events without a router set are handled by the DefaultHandler, as they were before there were routers for them
+	if !h.configured(evtType) {
+		evtType = ""
+	}
 	switch evtType {
 	case "APIGatewayProxyRequest":
 		var e APIGatewayProxyRequest
@@ -112,6 +194,30 @@ func (h *Handlers) eventHandler(ctx context.Context, d *HandlerDependencies, evt
 			return h.Router.LambdaHandler(ctx, d, e)
 		}
 		log.Println("Could not decode APIGatewayProxyRequest event", err)
+	case "ALBTargetGroupRequest":
+		var e ALBTargetGroupRequest
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.Router.LambdaHandlerALB(ctx, d, e)
+		}
+		log.Println("Could not decode ALBTargetGroupRequest event", decodeErr)
+		err = decodeErr
+	case "APIGatewayV2HTTPRequest":
+		var e APIGatewayV2HTTPRequest
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.Router.LambdaHandlerV2(ctx, d, e)
+		}
+		log.Println("Could not decode APIGatewayV2HTTPRequest event", decodeErr)
+		err = decodeErr
+	case "WebSocketRequest":
+		var e WebSocketRequest
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.WebSocketRouter.LambdaHandler(ctx, d, e)
+		}
+		log.Println("Could not decode WebSocketRequest event", decodeErr)
+		err = decodeErr
 	case "AegisTask":
 		// Task handlers have no return
 		// Tasker takes a simple map[string]interface{} - not a struct (like some other events).
@@ -134,11 +240,62 @@ func (h *Handlers) eventHandler(ctx context.Context, d *HandlerDependencies, evt
 		} else {
 			log.Println("Could not decode S3Event", decodeErr)
 		}
+	case "SQSEvent":
+		var e SQSEvent
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.SQSRouter.LambdaHandler(ctx, d, e)
+		}
+		log.Println("Could not decode SQSEvent", decodeErr)
+		err = decodeErr
+	case "SNSEvent":
+		var e SNSEvent
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			err = h.SNSRouter.LambdaHandler(ctx, d, e)
+		} else {
+			log.Println("Could not decode SNSEvent", decodeErr)
+		}
+	case "DynamoDBStreamEvent":
+		var e DynamoDBStreamEvent
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.DynamoDBStreamRouter.LambdaHandler(ctx, d, e)
+		}
+		log.Println("Could not decode DynamoDBStreamEvent", decodeErr)
+		err = decodeErr
+	case "KinesisEvent":
+		var e KinesisEvent
+		// Record data is base64 encoded, decoding by way of JSON takes care of that for []byte
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			return h.KinesisRouter.LambdaHandler(ctx, d, e)
+		}
+		log.Println("Could not decode KinesisEvent", decodeErr)
+		err = decodeErr
+	case "CloudWatchEvent":
+		var e CloudWatchEvent
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			err = h.EventBridgeRouter.LambdaHandler(ctx, d, e)
+		} else {
+			log.Println("Could not decode CloudWatchEvent", decodeErr)
+			err = decodeErr
+		}
 	case "CognitoTrigger":
 		// There's so many different formats here, routing for each is a bit silly.
 		// So send map[string]interface{}
 		// The handler itself can unmarshal using structs found in cognito_trigger_types.go
 		return h.CognitoRouter.LambdaHandler(ctx, d, evt)
+	case "CognitoEvent":
+		var e CognitoEvent
+		decodeErr := decodeJSONEvent(evt, &e)
+		if decodeErr == nil {
+			// The event, with any changes to its dataset records, must be returned to Cognito Sync
+			return h.CognitoSyncRouter.LambdaHandler(ctx, d, e)
+		}
+		log.Println("Could not decode CognitoEvent", decodeErr)
+		err = decodeErr
 	default:
 		log.Println("Could not determine Lambda event type, using DefaultHandler.")
 		// If a default handler is not set, return an error about it.
@@ -0,0 +1,135 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandlers(t *testing.T) {
+	defaultHandled := false
+	h := Handlers{
+		DefaultHandler: func(ctx context.Context, d *HandlerDependencies, evt *map[string]interface{}) (interface{}, error) {
+			defaultHandled = true
+			return "default", nil
+		},
+	}
+	handle := func(evt map[string]interface{}) interface{} {
+		defaultHandled = false
+		res, _ := h.eventHandler(context.Background(), &HandlerDependencies{}, evt)
+		return res
+	}
+
+	Convey("eventHandler", t, func() {
+		Convey("Should use the DefaultHandler for SQS events without an SQSRouter", func() {
+			res := handle(map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"eventSource": "aws:sqs", "messageId": "1", "body": "hello"},
+				},
+			})
+			So(defaultHandled, ShouldBeTrue)
+			So(res, ShouldEqual, "default")
+		})
+
+		Convey("Should use the DefaultHandler for SNS events without an SNSRouter", func() {
+			handle(map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"EventSource": "aws:sns", "Sns": map[string]interface{}{"Message": "hello"}},
+				},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for DynamoDB stream events without a DynamoDBStreamRouter", func() {
+			handle(map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"eventSource": "aws:dynamodb", "eventName": "INSERT"},
+				},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for Kinesis events without a KinesisRouter", func() {
+			handle(map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"eventSource": "aws:kinesis", "kinesis": map[string]interface{}{"data": "e30="}},
+				},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for WebSocket events without a WebSocketRouter", func() {
+			handle(map[string]interface{}{
+				"requestContext": map[string]interface{}{"routeKey": "$connect", "connectionId": "abc="},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for HTTP API events without a Router", func() {
+			handle(map[string]interface{}{"version": "2.0", "rawPath": "/", "routeKey": "$default"})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for load balancer events without a Router", func() {
+			handle(map[string]interface{}{
+				"httpMethod":     "GET",
+				"path":           "/",
+				"requestContext": map[string]interface{}{"elb": map[string]interface{}{"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/aegis/1"}},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+
+		Convey("Should use the DefaultHandler for scheduled events without an EventBridgeRouter", func() {
+			res := handle(map[string]interface{}{
+				"id":          "cdc73f9d-aea9-11e3-9d5a-835b769c0d9c",
+				"source":      "aws.events",
+				"detail-type": "Scheduled Event",
+				"resources":   []interface{}{"arn:aws:events:us-east-1:123456789012:rule/my-schedule"},
+				"detail":      map[string]interface{}{},
+			})
+			So(defaultHandled, ShouldBeTrue)
+			So(res, ShouldEqual, "default")
+		})
+
+		Convey("Should use the EventBridgeRouter for scheduled events when it is set", func() {
+			routed := false
+			withRouter := h
+			withRouter.EventBridgeRouter = NewEventBridgeRouter(func(ctx context.Context, d *HandlerDependencies, evt *CloudWatchEvent) error {
+				routed = true
+				return nil
+			})
+			defaultHandled = false
+			withRouter.eventHandler(context.Background(), &HandlerDependencies{}, map[string]interface{}{
+				"source":      "aws.events",
+				"detail-type": "Scheduled Event",
+				"detail":      map[string]interface{}{},
+			})
+			So(routed, ShouldBeTrue)
+			So(defaultHandled, ShouldBeFalse)
+		})
+
+		Convey("Should use the DefaultHandler for Cognito Sync events without a CognitoSyncRouter", func() {
+			handle(map[string]interface{}{
+				"identityPoolId": "us-east-1:abc",
+				"datasetName":    "settings",
+				"datasetRecords": map[string]interface{}{},
+			})
+			So(defaultHandled, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// These types are for API Gateway HTTP APIs (payload format version 2.0) and Lambda Function URLs, which share
+// the same event format. They are not in the vendored aws-lambda-go package.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-develop-integrations-lambda.html
+
+// APIGatewayV2HTTPRequest is the event sent by HTTP APIs and Function URLs
+type APIGatewayV2HTTPRequest struct {
+	Version               string                         `json:"version"`
+	RouteKey              string                         `json:"routeKey"`
+	RawPath               string                         `json:"rawPath"`
+	RawQueryString        string                         `json:"rawQueryString"`
+	Cookies               []string                       `json:"cookies"`
+	Headers               map[string]string              `json:"headers"`
+	QueryStringParameters map[string]string              `json:"queryStringParameters"`
+	PathParameters        map[string]string              `json:"pathParameters"`
+	StageVariables        map[string]string              `json:"stageVariables"`
+	RequestContext        APIGatewayV2HTTPRequestContext `json:"requestContext"`
+	Body                  string                         `json:"body"`
+	IsBase64Encoded       bool                           `json:"isBase64Encoded"`
+}
+
+// APIGatewayV2HTTPRequestContext holds request information for an APIGatewayV2HTTPRequest
+type APIGatewayV2HTTPRequestContext struct {
+	AccountID    string                 `json:"accountId"`
+	APIID        string                 `json:"apiId"`
+	Authorizer   map[string]interface{} `json:"authorizer"`
+	DomainName   string                 `json:"domainName"`
+	DomainPrefix string                 `json:"domainPrefix"`
+	HTTP         struct {
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		Protocol  string `json:"protocol"`
+		SourceIP  string `json:"sourceIp"`
+		UserAgent string `json:"userAgent"`
+	} `json:"http"`
+	RequestID string `json:"requestId"`
+	RouteKey  string `json:"routeKey"`
+	Stage     string `json:"stage"`
+	Time      string `json:"time"`
+	TimeEpoch int64  `json:"timeEpoch"`
+}
+
+// APIGatewayV2HTTPResponse is the response for HTTP APIs and Function URLs. Unlike APIGatewayProxyResponse,
+// cookies are returned separately from the headers so that more than one can be set.
+type APIGatewayV2HTTPResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+	Cookies         []string          `json:"cookies,omitempty"`
+}
+
+// LambdaHandlerV2 handles HTTP API (payload format version 2.0) and Function URL events. The event is normalized
+// into an APIGatewayProxyRequest so the same routes, middleware and RouteHandlers are used as with REST APIs.
+func (r *Router) LambdaHandlerV2(ctx context.Context, d *HandlerDependencies, req APIGatewayV2HTTPRequest) (APIGatewayV2HTTPResponse, error) {
+	res, err := r.LambdaHandler(ctx, d, req.proxyRequest())
+	return res.v2HTTPResponse(), err
+}
+
+// proxyRequest converts the v2 event into an APIGatewayProxyRequest
+func (req *APIGatewayV2HTTPRequest) proxyRequest() APIGatewayProxyRequest {
+	proxyReq := APIGatewayProxyRequest{
+		Path:           req.RawPath,
+		HTTPMethod:     req.RequestContext.HTTP.Method,
+		Headers:        map[string]string{},
+		PathParameters: req.PathParameters,
+		StageVariables: req.StageVariables,
+		Body:           req.Body,
+	}
+
+	// HTTP APIs include the stage in the path unless it's the $default stage, REST APIs never do.
+	stage := req.RequestContext.Stage
+	if stage != "" && stage != "$default" {
+		if proxyReq.Path == "/"+stage {
+			proxyReq.Path = "/"
+		} else if strings.HasPrefix(proxyReq.Path, "/"+stage+"/") {
+			proxyReq.Path = strings.TrimPrefix(proxyReq.Path, "/"+stage)
+		}
+	}
+
+	// Route keys look like "GET /pets/{id}" (or "$default")
+	if i := strings.Index(req.RouteKey, " "); i > -1 {
+		proxyReq.Resource = req.RouteKey[i+1:]
+	}
+
+	for k, v := range req.Headers {
+		proxyReq.Headers[k] = v
+	}
+	// Cookies are sent separately, put them back into the header so Cookie() and Cookies() work.
+	if len(req.Cookies) > 0 {
+		proxyReq.Headers[HeaderCookie] = strings.Join(req.Cookies, "; ")
+	}
+
+	// Repeated querystring parameters are comma separated in the v2 event. REST APIs use the last value.
+	proxyReq.QueryStringParameters = req.QueryStringParameters
+	if values, err := url.ParseQuery(req.RawQueryString); err == nil && len(values) > 0 {
+		proxyReq.QueryStringParameters = map[string]string{}
+		for k, v := range values {
+			proxyReq.QueryStringParameters[k] = v[len(v)-1]
+		}
+	}
+
+	proxyReq.Body, proxyReq.IsBase64Encoded = base64Body(req.Body, req.IsBase64Encoded)
+
+	proxyReq.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:    req.RequestContext.AccountID,
+		APIID:        req.RequestContext.APIID,
+		Stage:        stage,
+		RequestID:    req.RequestContext.RequestID,
+		ResourcePath: proxyReq.Resource,
+		HTTPMethod:   proxyReq.HTTPMethod,
+		Authorizer:   v2Authorizer(req.RequestContext.Authorizer),
+	}
+	proxyReq.RequestContext.Identity.SourceIP = req.RequestContext.HTTP.SourceIP
+	proxyReq.RequestContext.Identity.UserAgent = req.RequestContext.HTTP.UserAgent
+
+	return proxyReq
+}
+
+// base64Body returns a request body base64 encoded. The REST API is deployed with all binary media types, so request
+// bodies are always base64 encoded and GetBody() expects that. HTTP APIs and load balancers only encode binary bodies.
+func base64Body(body string, isBase64Encoded bool) (string, bool) {
+	if isBase64Encoded || body == "" {
+		return body, isBase64Encoded
+	}
+	return base64.StdEncoding.EncodeToString([]byte(body)), true
+}
+
+// v2Authorizer flattens the v2 authorizer context so that JWT claims are found under "claims",
+// just like a REST API with a Cognito user pool authorizer, and Lambda authorizer context is at the top level.
+func v2Authorizer(authorizer map[string]interface{}) map[string]interface{} {
+	if authorizer == nil {
+		return nil
+	}
+	flat := map[string]interface{}{}
+	for k, v := range authorizer {
+		switch k {
+		case "jwt", "lambda":
+			if m, ok := v.(map[string]interface{}); ok {
+				for mk, mv := range m {
+					flat[mk] = mv
+				}
+				continue
+			}
+		}
+		flat[k] = v
+	}
+	return flat
+}
+
+// v2HTTPResponse converts the response for HTTP APIs and Function URLs, moving any Set-Cookie headers to cookies
+func (res *APIGatewayProxyResponse) v2HTTPResponse() APIGatewayV2HTTPResponse {
+	v2Res := APIGatewayV2HTTPResponse{
+		StatusCode:      res.StatusCode,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+	}
+	// Sorted so the cookies are always in the same order
+	keys := make([]string, 0, len(res.Headers))
+	for k := range res.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.EqualFold(k, HeaderSetCookie) {
+			v2Res.Cookies = append(v2Res.Cookies, res.Headers[k])
+			continue
+		}
+		if v2Res.Headers == nil {
+			v2Res.Headers = make(map[string]string)
+		}
+		v2Res.Headers[k] = res.Headers[k]
+	}
+	return v2Res
+}
@@ -0,0 +1,105 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHTTPAPI(t *testing.T) {
+	Convey("proxyRequest", t, func() {
+		req := APIGatewayV2HTTPRequest{
+			Version:        "2.0",
+			RouteKey:       "ANY /{proxy+}",
+			RawPath:        "/prod/pets/42",
+			RawQueryString: "color=brown&color=black&name=Rex%20Jr",
+			Cookies:        []string{"session=abc", "theme=dark"},
+			Headers:        map[string]string{"content-type": "application/json"},
+			PathParameters: map[string]string{"proxy": "pets/42"},
+			Body:           `{"name":"Rex"}`,
+		}
+		req.RequestContext.Stage = "prod"
+		req.RequestContext.HTTP.Method = "POST"
+		req.RequestContext.HTTP.SourceIP = "127.0.0.1"
+		req.RequestContext.Authorizer = map[string]interface{}{
+			"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "1234"}},
+		}
+		proxyReq := req.proxyRequest()
+
+		Convey("Should set the method and path without the stage", func() {
+			So(proxyReq.HTTPMethod, ShouldEqual, "POST")
+			So(proxyReq.Path, ShouldEqual, "/pets/42")
+			So(proxyReq.Resource, ShouldEqual, "/{proxy+}")
+			So(proxyReq.PathParameters["proxy"], ShouldEqual, "pets/42")
+		})
+
+		Convey("Should not strip the path for the $default stage", func() {
+			req.RawPath = "/pets/42"
+			req.RequestContext.Stage = "$default"
+			So(req.proxyRequest().Path, ShouldEqual, "/pets/42")
+		})
+
+		Convey("Should decode the querystring using the last value", func() {
+			So(proxyReq.GetParam("color"), ShouldEqual, "black")
+			So(proxyReq.GetParam("name"), ShouldEqual, "Rex Jr")
+		})
+
+		Convey("Should set the cookie header", func() {
+			cookie, err := proxyReq.Cookie("theme")
+			So(err, ShouldBeNil)
+			So(cookie.Value, ShouldEqual, "dark")
+		})
+
+		Convey("Should base64 encode the body", func() {
+			body, err := proxyReq.GetJSONBody()
+			So(err, ShouldBeNil)
+			So(body["name"], ShouldEqual, "Rex")
+		})
+
+		Convey("Should set the identity and JWT claims", func() {
+			So(proxyReq.IP(), ShouldEqual, "127.0.0.1")
+			So(proxyReq.RequestContext.Authorizer["claims"], ShouldResemble, map[string]interface{}{"sub": "1234"})
+		})
+	})
+
+	Convey("v2HTTPResponse", t, func() {
+		Convey("Should return cookies separately from the headers", func() {
+			res := APIGatewayProxyResponse{}
+			res.JSON(200, map[string]string{"foo": "bar"})
+			res.SetCookie(&http.Cookie{Name: "session", Value: "abc"})
+			res.SetCookie(&http.Cookie{Name: "theme", Value: "dark"})
+			v2Res := res.v2HTTPResponse()
+			So(v2Res.StatusCode, ShouldEqual, 200)
+			So(v2Res.Headers, ShouldResemble, map[string]string{HeaderContentType: MIMEApplicationJSONCharsetUTF8})
+			So(v2Res.Cookies, ShouldHaveLength, 2)
+			So(v2Res.Cookies, ShouldContain, "session=abc")
+			So(v2Res.Cookies, ShouldContain, "theme=dark")
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify an APIGatewayV2HTTPRequest", func() {
+			evt := map[string]interface{}{
+				"version":        "2.0",
+				"rawPath":        "/",
+				"requestContext": map[string]interface{}{"http": map[string]interface{}{"method": "GET"}},
+			}
+			So(getType(evt), ShouldEqual, "APIGatewayV2HTTPRequest")
+		})
+	})
+}
@@ -205,6 +205,37 @@ func (res *APIGatewayProxyResponse) SetHeader(key string, value string) {
 	res.Headers[key] = value
 }
 
+// SetCookie will add a Set-Cookie header to the response for the given cookie. Headers are a map, so each additional
+// cookie is set under a different casing of the header name (ie. "set-Cookie"). HTTP header names are case-insensitive
+// and API Gateway returns each of them, allowing more than one cookie to be set.
+func (res *APIGatewayProxyResponse) SetCookie(cookie *http.Cookie) {
+	key := HeaderSetCookie
+	for i := 1; res.Headers[key] != ""; i++ {
+		key = setCookieHeaderVariant(i)
+	}
+	res.SetHeader(key, cookie.String())
+}
+
+// setCookieHeaderVariant returns the Set-Cookie header name with the case of its letters swapped by the bits of n
+func setCookieHeaderVariant(n int) string {
+	b := []byte(HeaderSetCookie)
+	bit := uint(0)
+	for i, c := range b {
+		if c == '-' {
+			continue
+		}
+		if n&(1<<bit) != 0 {
+			if c >= 'a' && c <= 'z' {
+				b[i] = c - 32
+			} else {
+				b[i] = c + 32
+			}
+		}
+		bit++
+	}
+	return string(b)
+}
+
 // SetStatus will set the status code for the response.
 func (res *APIGatewayProxyResponse) SetStatus(status int) {
 	res.StatusCode = status
@@ -0,0 +1,351 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchema is a JSON Schema, ie. {"type": "object", "required": ["ip"], "properties": {"ip": {"type": "string"}}}
+// Validation supports the commonly used keywords: type, enum, const, properties, required, additionalProperties,
+// items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf, oneOf and not. Other keywords (such as title, description, format and $ref) are ignored.
+// http://json-schema.org/understanding-json-schema/
+type JSONSchema map[string]interface{}
+
+// JSONSchemaError holds every problem found when validating a value against a JSONSchema
+type JSONSchemaError struct {
+	Problems []string
+}
+
+// Error returns the problems found, satisfying the error interface
+func (e *JSONSchemaError) Error() string {
+	return "does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// ParseJSONSchema returns a JSONSchema from a JSON string. An error is returned if it's not valid JSON or if it has
+// a pattern that isn't a valid regular expression.
+func ParseJSONSchema(s string) (JSONSchema, error) {
+	var schema JSONSchema
+	if err := json.Unmarshal([]byte(s), &schema); err != nil {
+		return nil, err
+	}
+	if _, err := newJSONSchemaValidator(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// MustParseJSONSchema is the same as ParseJSONSchema only it will panic if the schema is not valid
+func MustParseJSONSchema(s string) JSONSchema {
+	schema, err := ParseJSONSchema(s)
+	if err != nil {
+		panic("invalid JSON schema: " + err.Error())
+	}
+	return schema
+}
+
+// Validate returns a *JSONSchemaError if the value (anything that can be marshaled to JSON) does not match the schema.
+// Other errors are returned for schemas that can't be used, ie. with a pattern that isn't a valid regular expression.
+func (s JSONSchema) Validate(v interface{}) error {
+	validator, err := newJSONSchemaValidator(s)
+	if err != nil {
+		return err
+	}
+	return validator.validate(v)
+}
+
+// jsonSchemaValidator validates values against a normalized schema, with the schema's patterns compiled
+// so a schema used for many values (ie. a ProcedureContract's) is only prepared once
+type jsonSchemaValidator struct {
+	schema   map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// newJSONSchemaValidator returns a validator for the schema, or an error if the schema can't be marshaled to JSON
+// or one of its patterns isn't a valid regular expression
+func newJSONSchemaValidator(s JSONSchema) (*jsonSchemaValidator, error) {
+	normalized, err := normalizeJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	schema, _ := normalized.(map[string]interface{})
+	sv := &jsonSchemaValidator{schema: schema, patterns: map[string]*regexp.Regexp{}}
+	if err := sv.compilePatterns(schema); err != nil {
+		return nil, err
+	}
+	return sv, nil
+}
+
+// compilePatterns compiles the patterns of a (normalized) schema and its subschemas
+func (sv *jsonSchemaValidator) compilePatterns(schema map[string]interface{}) error {
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, compiled := sv.patterns[pattern]; !compiled {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %s: %v", pattern, err)
+			}
+			sv.patterns[pattern] = re
+		}
+	}
+
+	var subschemas []interface{}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, p := range properties {
+		subschemas = append(subschemas, p)
+	}
+	for _, keyword := range []string{"additionalProperties", "items", "not"} {
+		subschemas = append(subschemas, schema[keyword])
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		list, _ := schema[keyword].([]interface{})
+		subschemas = append(subschemas, list...)
+	}
+	for _, sub := range subschemas {
+		if m, ok := sub.(map[string]interface{}); ok {
+			if err := sv.compilePatterns(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate returns a *JSONSchemaError if the value does not match the schema
+func (sv *jsonSchemaValidator) validate(v interface{}) error {
+	value, err := normalizeJSON(v)
+	if err != nil {
+		return err
+	}
+	if problems := sv.validateSchema(sv.schema, value, ""); len(problems) > 0 {
+		return &JSONSchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// normalizeJSON returns a value by way of JSON so that []string, int, structs, etc. compare the same as decoded JSON
+func normalizeJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(b, &normalized)
+	return normalized, err
+}
+
+// validateSchema returns the problems found validating a (normalized) value against a (normalized) schema.
+// Problems are prefixed with the JSON pointer to the value, ie. "/address/zip: expected string".
+func (sv *jsonSchemaValidator) validateSchema(schema map[string]interface{}, v interface{}, path string) []string {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		problems = append(problems, p+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok && !matchJSONType(t, v) {
+		problem("expected %s, got %s", jsonTypeNames(t), jsonTypeOf(v))
+		// Nothing else will make sense when the type is wrong
+		return problems
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problem("must be one of %s", jsonString(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		problem("must be %s", jsonString(c))
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		problems = append(problems, sv.validateObject(schema, value, path)...)
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(value)) < min {
+			problem("must have at least %v items", min)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(value)) > max {
+			problem("must have at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				problems = append(problems, sv.validateSchema(items, item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+		if min, ok := schema["minLength"].(float64); ok && length < min {
+			problem("must be at least %v characters", min)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && length > max {
+			problem("must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !sv.patterns[pattern].MatchString(value) {
+			problem("must match pattern %s", pattern)
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && value < min {
+			problem("must be >= %v", min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && value > max {
+			problem("must be <= %v", max)
+		}
+		if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+			problem("must be > %v", min)
+		}
+		if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+			problem("must be < %v", max)
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, s := range allOf {
+			sub, _ := s.(map[string]interface{})
+			problems = append(problems, sv.validateSchema(sub, v, path)...)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && sv.countMatches(anyOf, v, path) == 0 {
+		problem("must match at least one schema in anyOf")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok && sv.countMatches(oneOf, v, path) != 1 {
+		problem("must match exactly one schema in oneOf")
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && len(sv.validateSchema(not, v, path)) == 0 {
+		problem("must not match the schema in not")
+	}
+
+	return problems
+}
+
+// validateObject returns the problems found validating an object's properties
+func (sv *jsonSchemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) []string {
+	var problems []string
+	required, _ := schema["required"].([]interface{})
+	for _, r := range required {
+		if name, ok := r.(string); ok {
+			if _, exists := obj[name]; !exists {
+				problems = append(problems, path+"/"+name+": is required")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	// Sorted so problems are always reported in the same order
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if p, ok := properties[name]; ok {
+			sub, _ := p.(map[string]interface{})
+			problems = append(problems, sv.validateSchema(sub, obj[name], path+"/"+name)...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				problems = append(problems, path+"/"+name+": is not allowed")
+			}
+		case map[string]interface{}:
+			problems = append(problems, sv.validateSchema(additional, obj[name], path+"/"+name)...)
+		}
+	}
+	return problems
+}
+
+// countMatches returns the number of schemas the value matches
+func (sv *jsonSchemaValidator) countMatches(schemas []interface{}, v interface{}, path string) int {
+	matches := 0
+	for _, s := range schemas {
+		sub, _ := s.(map[string]interface{})
+		if len(sv.validateSchema(sub, v, path)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// matchJSONType returns whether or not a value is of the schema's type, which can be a type name or an array of them
+func matchJSONType(t interface{}, v interface{}) bool {
+	types, ok := t.([]interface{})
+	if !ok {
+		types = []interface{}{t}
+	}
+	actual := jsonTypeOf(v)
+	for _, name := range types {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON Schema type name of a (normalized) value
+func jsonTypeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// jsonTypeNames returns the schema's type (a type name or an array of them) for problem messages
+func jsonTypeNames(t interface{}) string {
+	if types, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(types))
+		for _, name := range types {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonString returns a value as JSON for problem messages
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,79 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := MustParseJSONSchema(`{
+		"type": "object",
+		"required": ["id", "tags"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string", "enum": ["a", "b"]}},
+			"score": {"type": ["number", "null"], "exclusiveMaximum": 10},
+			"kind": {"oneOf": [{"const": "x"}, {"const": "y"}]},
+			"name": {"not": {"type": "integer"}}
+		},
+		"additionalProperties": {"type": "boolean"}
+	}`)
+
+	Convey("Validate", t, func() {
+		Convey("Should return nil for a matching value", func() {
+			So(schema.Validate(map[string]interface{}{"id": 3, "tags": []string{"a"}, "score": nil, "kind": "y", "flag": true}), ShouldBeNil)
+		})
+
+		Convey("Should return every problem found", func() {
+			err := schema.Validate(map[string]interface{}{"id": 1.5, "tags": []string{"c"}, "score": 10, "kind": "z", "name": 1, "flag": "yes"})
+			So(err, ShouldHaveSameTypeAs, &JSONSchemaError{})
+			So(err.(*JSONSchemaError).Problems, ShouldResemble, []string{
+				"/flag: expected boolean, got string",
+				"/id: expected integer, got number",
+				"/kind: must match exactly one schema in oneOf",
+				"/name: must not match the schema in not",
+				"/score: must be < 10",
+				"/tags/0: must be one of [\"a\",\"b\"]",
+			})
+		})
+
+		Convey("Should report missing required properties", func() {
+			err := schema.Validate(map[string]interface{}{})
+			So(err.Error(), ShouldEqual, "does not match schema: /id: is required; /tags: is required")
+		})
+
+		Convey("Should validate the type of the root value", func() {
+			So(schema.Validate([]string{}).Error(), ShouldEqual, "does not match schema: /: expected object, got array")
+		})
+
+		Convey("Should return an error that isn't a *JSONSchemaError for an invalid pattern", func() {
+			err := JSONSchema{"items": map[string]interface{}{"pattern": "[0-9"}}.Validate([]string{"1"})
+			So(err, ShouldNotBeNil)
+			_, ok := err.(*JSONSchemaError)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("ParseJSONSchema", t, func() {
+		Convey("Should return an error for an invalid pattern", func() {
+			_, err := ParseJSONSchema(`{"properties": {"zip": {"type": "string", "pattern": "[0-9"}}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "invalid pattern [0-9")
+		})
+	})
+}
@@ -0,0 +1,238 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JSON-RPC 2.0 error codes, see https://www.jsonrpc.org/specification#error_object
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is used for errors returned by handlers with an RPCError code of their own
+	JSONRPCServerError = -32000
+)
+
+// JSONRPCRequest is a JSON-RPC 2.0 request. Requests without an ID are notifications, which get no response.
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// JSONRPCResponse is a JSON-RPC 2.0 response, holding either the result or an error
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSONRPCError is a JSON-RPC 2.0 error object. Errors returned by procedures include the *RPCError as data.
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// jsonrpcNullID is the ID of responses to requests whose ID could not be determined
+var jsonrpcNullID = json.RawMessage("null")
+
+// JSONRPC will handle JSON-RPC 2.0 requests POSTed to the given path with the RPCRouter's procedures, calling the
+// same RPCHandlers that are called by other Lambdas. Batches and notifications are supported. Router middleware
+// set with Use() runs for these requests as well as the given middleware, so they can be used for authentication.
+// Procedures are called with the request's params object (by-position params are not supported) and errors
+// returned by them are mapped to JSON-RPC error objects, internal errors are logged and only return "Internal error".
+// The RPCRouter's fall through handler is never called, unknown methods return a "Method not found" error instead.
+// So is the RPCDescribe procedure, unless RPCRouter.JSONRPCDescribe is set.
+func (r *Router) JSONRPC(path string, rpcRouter *RPCRouter, middleware ...Middleware) {
+	r.POST(path, rpcRouter.jsonrpcHandler, middleware...)
+}
+
+// jsonrpcHandler is a RouteHandler for a JSON-RPC 2.0 request or batch of requests
+func (r *RPCRouter) jsonrpcHandler(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+	body := jsonrpcBody(req)
+
+	// A batch is an array of requests, each getting a response in the array returned (unless it's a notification)
+	if bytes.HasPrefix(body, []byte("[")) {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			res.JSON(http.StatusOK, jsonrpcErrorResponse(jsonrpcNullID, JSONRPCParseError, "Parse error", err.Error()))
+			return nil
+		}
+		if len(batch) == 0 {
+			res.JSON(http.StatusOK, jsonrpcErrorResponse(jsonrpcNullID, JSONRPCInvalidRequest, "Invalid Request", nil))
+			return nil
+		}
+		responses := []*JSONRPCResponse{}
+		for _, raw := range batch {
+			if response := r.jsonrpcCall(ctx, d, raw); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		if len(responses) == 0 {
+			res.SetStatus(http.StatusNoContent)
+			return nil
+		}
+		res.JSON(http.StatusOK, responses)
+		return nil
+	}
+
+	if !json.Valid(body) {
+		res.JSON(http.StatusOK, jsonrpcErrorResponse(jsonrpcNullID, JSONRPCParseError, "Parse error", nil))
+		return nil
+	}
+	response := r.jsonrpcCall(ctx, d, body)
+	if response == nil {
+		res.SetStatus(http.StatusNoContent)
+		return nil
+	}
+	res.JSON(http.StatusOK, response)
+	return nil
+}
+
+// jsonrpcBody returns the request body, decoded when it's base64 encoded (see GetBody()). API Gateway encodes
+// request bodies, the local gateway does not. A body that can't be decoded is returned as nil, a parse error.
+func jsonrpcBody(req *APIGatewayProxyRequest) []byte {
+	body := req.Body
+	if req.IsBase64Encoded {
+		decoded, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		body = decoded
+	}
+	return []byte(strings.TrimSpace(body))
+}
+
+// jsonrpcCall calls the procedure for a single JSON-RPC request, returning its response or nil for notifications
+func (r *RPCRouter) jsonrpcCall(ctx context.Context, d *HandlerDependencies, raw json.RawMessage) *JSONRPCResponse {
+	var call JSONRPCRequest
+	if err := json.Unmarshal(raw, &call); err != nil || call.JSONRPC != "2.0" || call.Method == "" || !jsonrpcValidID(call.ID) {
+		return jsonrpcErrorResponse(jsonrpcNullID, JSONRPCInvalidRequest, "Invalid Request", nil)
+	}
+	id := call.ID
+	notification := id == nil
+
+	response := func(code int, message string, data interface{}) *JSONRPCResponse {
+		if notification {
+			return nil
+		}
+		return jsonrpcErrorResponse(id, code, message, data)
+	}
+
+	// Method names starting with "rpc." are reserved by the specification and the catalogue of procedures
+	// is only public when allowed
+	_, ok := r.handlerFor(call.Method)
+	if !ok || strings.HasPrefix(call.Method, "rpc.") || (call.Method == RPCDescribe && !r.JSONRPCDescribe) {
+		return response(JSONRPCMethodNotFound, "Method not found", nil)
+	}
+
+	params := map[string]interface{}{}
+	if len(call.Params) > 0 && !bytes.Equal(call.Params, jsonrpcNullID) {
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			return response(JSONRPCInvalidParams, "Invalid params", "params must be an object")
+		}
+	}
+	// Keys starting with "_rpc" are reserved for the framework (ie. the trace carrier), they can't be set by clients
+	evt := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		if !strings.HasPrefix(k, "_rpc") {
+			evt[k] = v
+		}
+	}
+	evt["_rpcName"] = call.Method
+
+	// Calling the RPCRouter's LambdaHandler traces the call and turns errors into an error envelope
+	result, _ := r.LambdaHandler(ctx, d, evt)
+	if rpcErr, ok := result[rpcErrorKey].(*RPCError); ok {
+		code, message := jsonrpcErrorCode(rpcErr.Code)
+		// Internal errors are logged rather than returned, they can reveal details that clients shouldn't see
+		if code == JSONRPCInternalError {
+			jsonrpcLogger(d).WithError(rpcErr).WithField("method", call.Method).Error("JSON-RPC procedure failed")
+			return response(code, message, nil)
+		}
+		if rpcErr.Message != "" {
+			message = rpcErr.Message
+		}
+		return response(code, message, rpcErr)
+	}
+	if notification {
+		return nil
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		jsonrpcLogger(d).WithError(err).WithField("method", call.Method).Error("could not marshal JSON-RPC result")
+		return jsonrpcErrorResponse(id, JSONRPCInternalError, "Internal error", nil)
+	}
+	return &JSONRPCResponse{JSONRPC: "2.0", Result: b, ID: id}
+}
+
+// jsonrpcLogger returns the logger for JSON-RPC errors, the dependencies' logger or framework.Log
+func jsonrpcLogger(d *HandlerDependencies) *logrus.Logger {
+	if d != nil && d.Log != nil {
+		return d.Log
+	}
+	return Log
+}
+
+// jsonrpcValidID returns whether or not a request ID is allowed: a string, number, null or not set (a notification)
+func jsonrpcValidID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+// jsonrpcErrorCode returns the JSON-RPC error code and default message for an RPCError code
+func jsonrpcErrorCode(code string) (int, string) {
+	switch code {
+	case RPCErrorInvalidRequest:
+		return JSONRPCInvalidParams, "Invalid params"
+	case RPCErrorInvalidResponse, RPCErrorInternal:
+		return JSONRPCInternalError, "Internal error"
+	}
+	return JSONRPCServerError, "Server error"
+}
+
+// jsonrpcErrorResponse returns a JSON-RPC error response
+func jsonrpcErrorResponse(id json.RawMessage, code int, message string, data interface{}) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error:   &JSONRPCError{Code: code, Message: message, Data: data},
+		ID:      id,
+	}
+}
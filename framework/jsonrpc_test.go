@@ -0,0 +1,197 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONRPC(t *testing.T) {
+	notified := 0
+	rpcRouter := NewRPCRouter()
+	rpcRouter.Handle("add", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		a, _ := evt["a"].(float64)
+		b, _ := evt["b"].(float64)
+		return map[string]interface{}{"sum": a + b}, nil
+	}, ProcedureContract{Input: MustParseJSONSchema(`{"required": ["a", "b"]}`)})
+	rpcRouter.Handle("notify", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		notified++
+		return nil, nil
+	})
+	rpcRouter.Handle("fail", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		if evt["coded"] == true {
+			return nil, NewRPCError("OutOfStock", "no more widgets", true)
+		}
+		return nil, errors.New("boom")
+	})
+
+	authorized := func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) bool {
+		if req.GetHeader("Authorization") != "secret" {
+			res.JSONError(401, errors.New("unauthorized"))
+			return false
+		}
+		return true
+	}
+	router := NewRouter(func(context.Context, *HandlerDependencies, *APIGatewayProxyRequest, *APIGatewayProxyResponse, url.Values) error {
+		return nil
+	})
+	router.JSONRPC("/rpc", rpcRouter, authorized)
+
+	post := func(body string) APIGatewayProxyResponse {
+		res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{
+			Path:            "/rpc",
+			HTTPMethod:      "POST",
+			Headers:         map[string]string{"Authorization": "secret"},
+			Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+			IsBase64Encoded: true,
+		})
+		return res
+	}
+	decode := func(res APIGatewayProxyResponse) map[string]interface{} {
+		var m map[string]interface{}
+		json.Unmarshal([]byte(res.Body), &m)
+		return m
+	}
+
+	Convey("JSONRPC", t, func() {
+		Convey("Should call the procedure and return its result", func() {
+			res := post(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": "abc"}`)
+			So(res.StatusCode, ShouldEqual, 200)
+			So(res.Body, ShouldEqual, `{"jsonrpc":"2.0","result":{"sum":3},"id":"abc"}`)
+
+			res = post(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 2}, "id": null}`)
+			So(res.Body, ShouldEqual, `{"jsonrpc":"2.0","result":{"sum":3},"id":null}`)
+		})
+
+		Convey("Should accept request bodies that aren't base64 encoded", func() {
+			res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{
+				Path:       "/rpc",
+				HTTPMethod: "POST",
+				Headers:    map[string]string{"Authorization": "secret"},
+				Body:       `{"jsonrpc": "2.0", "method": "add", "params": {"a": 2, "b": 2}, "id": 1}`,
+			})
+			So(res.Body, ShouldEqual, `{"jsonrpc":"2.0","result":{"sum":4},"id":1}`)
+		})
+
+		Convey("Should return a parse error for a body that isn't base64 encoded as claimed", func() {
+			res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{
+				Path:            "/rpc",
+				HTTPMethod:      "POST",
+				Headers:         map[string]string{"Authorization": "secret"},
+				Body:            `{"jsonrpc": "2.0", "method": "add", "params": {"a": 2, "b": 2}, "id": 1}`,
+				IsBase64Encoded: true,
+			})
+			So(decode(res)["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCParseError)
+		})
+
+		Convey("Should run the middleware", func() {
+			res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{
+				Path:       "/rpc",
+				HTTPMethod: "POST",
+				Body:       `{"jsonrpc": "2.0", "method": "add", "params": {"a": 2, "b": 2}, "id": 1}`,
+			})
+			So(res.StatusCode, ShouldEqual, 401)
+		})
+
+		Convey("Should not respond to notifications", func() {
+			notified = 0
+			res := post(`{"jsonrpc": "2.0", "method": "notify"}`)
+			So(res.StatusCode, ShouldEqual, 204)
+			So(res.Body, ShouldEqual, "")
+			So(notified, ShouldEqual, 1)
+		})
+
+		Convey("Should handle batches", func() {
+			notified = 0
+			res := post(`[
+				{"jsonrpc": "2.0", "method": "add", "params": {"a": 1, "b": 1}, "id": 1},
+				{"jsonrpc": "2.0", "method": "notify"},
+				{"jsonrpc": "2.0", "method": "missing", "id": 2},
+				1
+			]`)
+			var responses []JSONRPCResponse
+			So(json.Unmarshal([]byte(res.Body), &responses), ShouldBeNil)
+			So(responses, ShouldHaveLength, 3)
+			So(string(responses[0].Result), ShouldEqual, `{"sum":2}`)
+			So(responses[1].Error.Code, ShouldEqual, JSONRPCMethodNotFound)
+			So(string(responses[2].ID), ShouldEqual, "null")
+			So(responses[2].Error.Code, ShouldEqual, JSONRPCInvalidRequest)
+			So(notified, ShouldEqual, 1)
+		})
+
+		Convey("Should return 204 for a batch of notifications", func() {
+			res := post(`[{"jsonrpc": "2.0", "method": "notify"}, {"jsonrpc": "2.0", "method": "notify", "params": {}}]`)
+			So(res.StatusCode, ShouldEqual, 204)
+		})
+
+		Convey("Should return parse and invalid request errors", func() {
+			So(decode(post(`{"jsonrpc": "2.0", "method"`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCParseError)
+			So(decode(post(`[]`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCInvalidRequest)
+			So(decode(post(`{"jsonrpc": "1.0", "method": "add", "id": 1}`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCInvalidRequest)
+			So(decode(post(`{"jsonrpc": "2.0", "method": "add", "id": {}}`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCInvalidRequest)
+		})
+
+		Convey("Should not call the fall through handler or reserved methods", func() {
+			So(decode(post(`{"jsonrpc": "2.0", "method": "*", "id": 1}`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCMethodNotFound)
+			So(decode(post(`{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCMethodNotFound)
+		})
+
+		Convey("Should only call the describe procedure when allowed", func() {
+			So(decode(post(`{"jsonrpc": "2.0", "method": "__describe", "id": 1}`))["error"].(map[string]interface{})["code"], ShouldEqual, JSONRPCMethodNotFound)
+
+			rpcRouter.JSONRPCDescribe = true
+			defer func() { rpcRouter.JSONRPCDescribe = false }()
+			result := decode(post(`{"jsonrpc": "2.0", "method": "__describe", "id": 1}`))["result"].(map[string]interface{})
+			So(result["procedures"], ShouldContainKey, "add")
+		})
+
+		Convey("Should drop reserved params", func() {
+			var received map[string]interface{}
+			rpcRouter.Handle("echo", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+				received = evt
+				return nil, errors.New("boom")
+			})
+			e := decode(post(`{"jsonrpc": "2.0", "method": "echo", "params": {"a": 1, "_rpcAsync": true, "_rpcTrace": {"X-Amzn-Trace-Id": "Root=1"}}, "id": 1}`))["error"].(map[string]interface{})
+			So(e["code"], ShouldEqual, JSONRPCInternalError)
+			So(received, ShouldResemble, map[string]interface{}{"_rpcName": "echo", "a": float64(1)})
+		})
+
+		Convey("Should map procedure errors to JSON-RPC errors", func() {
+			e := decode(post(`{"jsonrpc": "2.0", "method": "add", "params": {"a": 1}, "id": 1}`))["error"].(map[string]interface{})
+			So(e["code"], ShouldEqual, JSONRPCInvalidParams)
+			So(e["data"].(map[string]interface{})["code"], ShouldEqual, RPCErrorInvalidRequest)
+
+			e = decode(post(`{"jsonrpc": "2.0", "method": "add", "params": [1, 2], "id": 1}`))["error"].(map[string]interface{})
+			So(e["code"], ShouldEqual, JSONRPCInvalidParams)
+
+			e = decode(post(`{"jsonrpc": "2.0", "method": "fail", "id": 1}`))["error"].(map[string]interface{})
+			So(e["code"], ShouldEqual, JSONRPCInternalError)
+			So(e["message"], ShouldEqual, "Internal error")
+			So(e, ShouldNotContainKey, "data")
+
+			e = decode(post(`{"jsonrpc": "2.0", "method": "fail", "params": {"coded": true}, "id": 1}`))["error"].(map[string]interface{})
+			So(e["code"], ShouldEqual, JSONRPCServerError)
+			So(e["message"], ShouldEqual, "no more widgets")
+			So(e["data"].(map[string]interface{})["retryable"], ShouldBeTrue)
+		})
+	})
+}
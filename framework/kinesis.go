@@ -0,0 +1,136 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/gobwas/glob"
+)
+
+// KinesisEventResponse is returned to Lambda so that processing resumes from the first failed record.
+// The event source mapping must have the ReportBatchItemFailures function response type enabled.
+// This is not in the vendored aws-lambda-go package.
+type KinesisEventResponse struct {
+	BatchItemFailures []KinesisBatchItemFailure `json:"batchItemFailures"`
+}
+
+// KinesisBatchItemFailure identifies a record (by sequence number) that could not be processed
+type KinesisBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// KinesisRouter struct provides an interface to handle Kinesis stream records (routed by stream ARN)
+// https://docs.aws.amazon.com/lambda/latest/dg/with-kinesis.html
+type KinesisRouter struct {
+	routes      []kinesisRoute
+	rootHandler KinesisHandler
+	Tracer      TraceStrategy
+}
+
+// KinesisHandler handles a single routed Kinesis record. The record's data has already been base64 decoded.
+type KinesisHandler func(context.Context, *HandlerDependencies, *KinesisEventRecord) error
+
+// kinesisRoute holds a handler along with the stream ARN glob it matches
+type kinesisRoute struct {
+	matcher glob.Glob
+	handler KinesisHandler
+}
+
+// LambdaHandler handles Kinesis events. Records are processed in order and processing stops at the first
+// failure, which is reported back to Lambda so the batch is retried from that record on.
+func (r *KinesisRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt KinesisEvent) (KinesisEventResponse, error) {
+	response := KinesisEventResponse{BatchItemFailures: []KinesisBatchItemFailure{}}
+
+	for i := range evt.Records {
+		record := KinesisEventRecord(evt.Records[i])
+
+		handler, ok := r.handlerFor(&record)
+		r.Tracer.Annotations = map[string]interface{}{
+			"KinesisStreamArn":      record.EventSourceArn,
+			"KinesisPartitionKey":   record.Kinesis.PartitionKey,
+			"KinesisSequenceNumber": record.Kinesis.SequenceNumber,
+		}
+		if !ok {
+			log.Println("using default fall through handler")
+			r.Tracer.Annotations["FallthroughHandler"] = true
+		}
+
+		err := r.Tracer.Capture(ctx, "KinesisHandler", func(ctx1 context.Context) error {
+			r.Tracer.AddAnnotations(ctx1)
+			r.Tracer.AddMetadata(ctx1)
+			d.Tracer = &r.Tracer
+			return handler(ctx1, d, &record)
+		})
+		if err != nil {
+			log.Println("could not process Kinesis record", record.Kinesis.SequenceNumber, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, KinesisBatchItemFailure{ItemIdentifier: record.Kinesis.SequenceNumber})
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// handlerFor returns the first registered handler matching the record's stream ARN,
+// or the fall through handler (with false) when nothing matched.
+func (r *KinesisRouter) handlerFor(record *KinesisEventRecord) (KinesisHandler, bool) {
+	for _, route := range r.routes {
+		if route.matcher.Match(record.EventSourceArn) {
+			return route.handler, true
+		}
+	}
+
+	// It's possible that the KinesisRouter wasn't created with NewKinesisRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *KinesisEventRecord) error { return nil }, false
+	}
+	return r.rootHandler, false
+}
+
+// UnmarshalData will unmarshal the record's JSON data into the given value (typically a pointer to a struct)
+func (record *KinesisEventRecord) UnmarshalData(v interface{}) error {
+	return json.Unmarshal(record.Kinesis.Data, v)
+}
+
+// Listen will start a Kinesis listener that handles incoming records
+func (r *KinesisRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewKinesisRouter simply returns a new KinesisRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewKinesisRouter(rootHandler ...KinesisHandler) *KinesisRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and the record is considered handled.
+	handler := func(context.Context, *HandlerDependencies, *KinesisEventRecord) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &KinesisRouter{
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for records from streams whose ARN matches the given glob
+func (r *KinesisRouter) Handle(streamArnMatch string, handler KinesisHandler) {
+	r.routes = append(r.routes, kinesisRoute{
+		matcher: glob.MustCompile(streamArnMatch),
+		handler: handler,
+	})
+}
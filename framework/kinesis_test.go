@@ -0,0 +1,123 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKinesisRouter(t *testing.T) {
+	testHandler := func(ctx context.Context, d *HandlerDependencies, record *KinesisEventRecord) error {
+		return nil
+	}
+
+	testRouter := NewKinesisRouter()
+	Convey("NewKinesisRouter", t, func() {
+		Convey("Should create a new KinesisRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Handle("arn:aws:kinesis:*:stream/clicks", testHandler)
+
+	// data is base64 for {"page":"/home"}
+	testEvt := `{"Records":[{
+		"eventSource":"aws:kinesis",
+		"eventSourceARN":"arn:aws:kinesis:us-east-1:123456789012:stream/clicks",
+		"kinesis":{
+			"partitionKey":"user-1",
+			"data":"eyJwYWdlIjoiL2hvbWUifQ==",
+			"sequenceNumber":"49590338271490256608559692538361571095921575989136588898",
+			"approximateArrivalTimestamp":1545084650.987
+		}
+	}]}`
+	var evtMap map[string]interface{}
+	json.Unmarshal([]byte(testEvt), &evtMap)
+	var evt KinesisEvent
+	decodeErr := decodeJSONEvent(evtMap, &evt)
+
+	Convey("Should decode a KinesisEvent", t, func() {
+		So(getType(evtMap), ShouldEqual, "KinesisEvent")
+		So(decodeErr, ShouldBeNil)
+		So(evt.Records, ShouldHaveLength, 1)
+	})
+
+	Convey("UnmarshalData", t, func() {
+		Convey("Should unmarshal the base64 decoded data", func() {
+			record := KinesisEventRecord(evt.Records[0])
+			var click map[string]string
+			err := record.UnmarshalData(&click)
+			So(err, ShouldBeNil)
+			So(click["page"], ShouldEqual, "/home")
+		})
+	})
+
+	Convey("handlerFor", t, func() {
+		Convey("Should match on stream ARN", func() {
+			record := KinesisEventRecord(evt.Records[0])
+			_, ok := testRouter.handlerFor(&record)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Should fall through for other streams", func() {
+			record := KinesisEventRecord(evt.Records[0])
+			record.EventSourceArn = "arn:aws:kinesis:us-east-1:123456789012:stream/orders"
+			_, ok := testRouter.handlerFor(&record)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		handled := []string{}
+		failingRouter := NewKinesisRouter(func(ctx context.Context, d *HandlerDependencies, record *KinesisEventRecord) error {
+			handled = append(handled, record.Kinesis.SequenceNumber)
+			if record.Kinesis.PartitionKey == "fail" {
+				return errors.New("could not process")
+			}
+			return nil
+		})
+		batch := func(partitionKeys ...string) KinesisEvent {
+			handled = []string{}
+			evt := KinesisEvent{Records: make([]events.KinesisEventRecord, len(partitionKeys))}
+			for i, key := range partitionKeys {
+				evt.Records[i].Kinesis.PartitionKey = key
+				evt.Records[i].Kinesis.SequenceNumber = strconv.Itoa(i + 1)
+			}
+			return evt
+		}
+
+		Convey("Should report the first failed record and stop processing", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, batch("ok", "fail", "ok"))
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldResemble, []KinesisBatchItemFailure{{ItemIdentifier: "2"}})
+			So(handled, ShouldResemble, []string{"1", "2"})
+		})
+
+		Convey("Should return an empty list when every record was processed", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, batch("ok", "ok"))
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldNotBeNil)
+			So(res.BatchItemFailures, ShouldBeEmpty)
+			So(handled, ShouldResemble, []string{"1", "2"})
+		})
+	})
+}
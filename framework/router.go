@@ -52,16 +52,30 @@ type RouteHandler func(context.Context, *HandlerDependencies, *APIGatewayProxyRe
 // means to keep processing the rest of the middleware chain, false means end.
 type Middleware func(context.Context, *HandlerDependencies, *APIGatewayProxyRequest, *APIGatewayProxyResponse, url.Values) bool
 
+// HandlerMiddleware wraps a RouteHandler, returning a RouteHandler that calls next. Unlike Middleware, it can run
+// code after the handler, with access to the response and the handler's error. See Wrap() and WrapHandler().
+type HandlerMiddleware func(next RouteHandler) RouteHandler
+
 // Router name says it all.
 type Router struct {
 	tree           *node
 	rootHandler    RouteHandler
 	middleware     []Middleware
+	wrappers       []HandlerMiddleware
 	l              *log.Logger
 	LoggingEnabled bool
 	URIVersion     string
 	GatewayPort    string
 	Tracer         TraceStrategy
+	// MethodNotAllowedHandler handles requests for paths without a route for the method, the Allow header
+	// is already set. By default a 405 Method Not Allowed error is returned.
+	MethodNotAllowedHandler RouteHandler
+	// OptionsHandler handles OPTIONS requests for paths without an OPTIONS route, the Allow header is already set.
+	// By default a 204 No Content response is returned.
+	OptionsHandler RouteHandler
+	// parent and prefix are set for route groups, see Group()
+	parent *Router
+	prefix string
 }
 
 var (
@@ -83,10 +97,20 @@ func (r *Router) Use(middleware ...Middleware) {
 }
 
 // Handle takes an http handler, method and pattern for a route.
+// Patterns can have named params (/users/:id), named params with a regular expression or type constraint
+// (/users/:id{[0-9]+} or /users/:id{int}, constraints can't contain a /) and a trailing wildcard (/files/*path).
+// Static segments take precedence over named params, which take precedence over a wildcard. Handle will panic
+// if the route was already added or is ambiguous with one that was, ie. /users/:id and /users/:name or
+// /users/:id{int} and /users/:n{[0-9]{3}} (constraints of params in the same segment must be provably disjoint).
 func (r *Router) Handle(method, path string, handler RouteHandler, middleware ...Middleware) {
 	if path[0] != '/' {
 		panic("Path has to start with a /.")
 	}
+	// Routes in a group are added to the parent with the group's prefix, running the group's middleware first
+	if r.parent != nil {
+		r.parent.Handle(method, joinRoutePath(r.prefix, path), r.groupHandler(handler), append([]Middleware{r.groupMiddleware()}, middleware...)...)
+		return
+	}
 	r.tree.addNode(method, r.URIVersion+path, handler, middleware...)
 }
 
@@ -137,6 +161,10 @@ func runMiddleware(ctx context.Context, d *HandlerDependencies, req *APIGatewayP
 
 // LambdaHandler is a native AWS Lambda Go handler function (no more shim).
 func (r *Router) LambdaHandler(ctx context.Context, d *HandlerDependencies, req APIGatewayProxyRequest) (APIGatewayProxyResponse, error) {
+	// Groups don't handle requests themselves, see Group()
+	if r.parent != nil {
+		return r.root().LambdaHandler(ctx, d, req)
+	}
 	// url.Values are typically used for qureystring parameters.
 	// However, this router uses them for path params.
 	// Querystring parameters can be picked up from the *Event though.
@@ -153,7 +181,7 @@ func (r *Router) LambdaHandler(ctx context.Context, d *HandlerDependencies, req
 
 	// use the Path and HTTPMethod from the event to figure out the route
 	node, _ := r.tree.traverse(strings.Split(req.Path, "/")[1:], params)
-	if handler := node.methods[req.HTTPMethod]; handler != nil {
+	if handler := r.routeFor(node, req.HTTPMethod); handler != nil {
 		// Middleware must return true in order to continue.
 		// If it returns false, it will catch and halt everything.
 		if !runMiddleware(ctx, d, &req, &res, params, handler.middleware...) {
@@ -178,14 +206,14 @@ func (r *Router) LambdaHandler(ctx context.Context, d *HandlerDependencies, req
 			d.Tracer = &r.Tracer
 			// I believe ctx1 is actually the same as ctx in this case. Capture() makes no copy of context.
 			// Context is immutable. So... To not be confusing, we'll use ctx1.
-			return handler.handler(ctx1, d, &req, &res, params)
+			return r.wrap(handler.handler)(ctx1, d, &req, &res, params)
 		})
 
 		// TODO: look at environment variable to see if XRay was disabled (env var on lambda or when running local server)
 		// Then just call handler and not the xray part above.
 		// handler.handler(ctx, &req, &res, params)
 	} else {
-		r.rootHandler(ctx, d, &req, &res, params)
+		r.wrap(r.rootHandler)(ctx, d, &req, &res, params)
 	}
 
 	// Returning an error from this handler is how AWS Lambda works, but when dealing with API Gateway, it doesn't make for
@@ -234,7 +262,8 @@ func (h gatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// use the Path and HTTPMethod from the event to figure out the route
 	node, _ := h.tree.traverse(strings.Split(req.Path, "/")[1:], params)
-	if handler := node.methods[req.HTTPMethod]; handler != nil {
+	router := Router(h)
+	if handler := router.routeFor(node, req.HTTPMethod); handler != nil {
 		// Middleware must return true in order to continue.
 		// If it returns false, it will catch and halt everything.
 		if !runMiddleware(ctx, d, req, &res, params, handler.middleware...) {
@@ -242,9 +271,12 @@ func (h gatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.proxyResponseToHTTPResponse(&res, w)
 			return
 		}
-		handler.handler(ctx, d, req, &res, params)
+		// Errors are handled the same way as LambdaHandler
+		if err := router.wrap(handler.handler)(ctx, d, req, &res, params); err != nil {
+			res.Error(500, err)
+		}
 	} else {
-		h.rootHandler(ctx, d, req, &res, params)
+		router.wrap(h.rootHandler)(ctx, d, req, &res, params)
 	}
 
 	// <-- Send the response
@@ -320,31 +352,14 @@ func (h gatewayHandler) requestToProxyRequest(r *http.Request) (context.Context,
 func (h gatewayHandler) proxyResponseToHTTPResponse(res *APIGatewayProxyResponse, w http.ResponseWriter) {
 	// transfer the headers into the HTTP Response
 	for k, v := range res.Headers {
+		// Multiple cookies are set under different casings of the Set-Cookie header, see SetCookie()
+		if strings.EqualFold(k, HeaderSetCookie) {
+			w.Header().Add(k, v)
+			continue
+		}
 		w.Header().Set(k, v)
 	}
 
-	// CORS. Allow everything since we are assumed to be running locally.
-	allowedHeaders := []string{
-		"Accept",
-		"Content-Type",
-		"Content-Length",
-		"Accept-Encoding",
-		"Authorization",
-		"X-CSRF-Token",
-		"X-Auth-Token",
-	}
-	allowedMethods := []string{
-		"POST",
-		"GET",
-		"OPTIONS",
-		"PUT",
-		"PATCH",
-		"DELETE",
-	}
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
-	w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
-
 	// The handler and middleware should have set everything on res
 	w.WriteHeader(res.StatusCode)
 
@@ -366,6 +381,10 @@ func (h gatewayHandler) proxyResponseToHTTPResponse(res *APIGatewayProxyResponse
 
 // Gateway will start a local web server to listen for events. Useful for testing locally.
 func (r *Router) Gateway() {
+	if r.parent != nil {
+		r.root().Gateway()
+		return
+	}
 	if r.GatewayPort == "" {
 		r.GatewayPort = ":9999"
 	}
@@ -0,0 +1,83 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Group returns a sub-router for routes under the given path prefix. The group's middleware, and any added to it
+// with Use(), runs after the Router's middleware and before each route's own middleware. HandlerMiddleware added
+// to it with Wrap() wraps the group's routes. Groups can be nested to any depth. Routes are still handled by the
+// Router the group was made from, a group's LambdaHandler(), Listen() and Gateway() use that Router. Groups can't be
+// mounted on another Router, mount the Router they were made from instead.
+func (r *Router) Group(prefix string, middleware ...Middleware) *Router {
+	if prefix == "" || prefix[0] != '/' {
+		panic("Group prefix has to start with a /.")
+	}
+	return &Router{
+		parent:     r,
+		prefix:     prefix,
+		middleware: middleware,
+	}
+}
+
+// Mount will add every route from another Router under the given path prefix. The other Router's middleware (added
+// with Use()) runs before each of its routes' own middleware and its HandlerMiddleware (added with Wrap()) wraps
+// each of its routes, but its fall through handler is not used. Routes are copied when mounted, so register them
+// on the other Router first.
+func (r *Router) Mount(prefix string, router *Router) {
+	if prefix == "" || prefix[0] != '/' {
+		panic("Mount prefix has to start with a /.")
+	}
+	// A group's routes are added to the Router it was made from, it has none of its own
+	if router.parent != nil {
+		panic("Mount can't mount a group, mount the Router it was made from.")
+	}
+	if router.tree == nil {
+		panic("Mount needs a Router made with NewRouter().")
+	}
+	mounted := router.groupMiddleware()
+	router.tree.walk("", func(method, path string, route *route) {
+		r.Handle(method, joinRoutePath(prefix, path), router.groupHandler(route.handler), append([]Middleware{mounted}, route.middleware...)...)
+	})
+}
+
+// root returns the Router a group was made from, or the Router itself when it's not a group
+func (r *Router) root() *Router {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// groupMiddleware returns middleware that runs the Router's middleware. It's looked up for each request,
+// so middleware added with Use() after routes were added still runs.
+func (r *Router) groupMiddleware() Middleware {
+	return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) bool {
+		return runMiddleware(ctx, d, req, res, params, r.middleware...)
+	}
+}
+
+// joinRoutePath returns a route's path under a prefix, ie. "/admin" and "/users" is "/admin/users" and "/admin" and "/" is "/admin"
+func joinRoutePath(prefix string, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "/" && prefix != "" {
+		return prefix
+	}
+	return prefix + path
+}
@@ -0,0 +1,138 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRouterGroup(t *testing.T) {
+	var calls []string
+	handler := func(name string) RouteHandler {
+		return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+			calls = append(calls, name+":"+params.Get("id"))
+			res.StatusCode = 200
+			return nil
+		}
+	}
+	middleware := func(name string, next bool) Middleware {
+		return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) bool {
+			calls = append(calls, name)
+			if !next {
+				res.StatusCode = 403
+			}
+			return next
+		}
+	}
+	request := func(r *Router, method string, path string) (int, string) {
+		calls = []string{}
+		res, _ := r.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{HTTPMethod: method, Path: path})
+		return res.StatusCode, strings.Join(calls, " ")
+	}
+
+	router := NewRouter(func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		calls = append(calls, "fallthrough")
+		res.StatusCode = 404
+		return nil
+	})
+	router.Use(middleware("router", true))
+
+	admin := router.Group("/admin", middleware("admin", true))
+	admin.GET("/", handler("dashboard"))
+	users := admin.Group("/users/")
+	users.GET("/:id", handler("user"), middleware("route", true))
+	// Middleware added after routes still runs for them
+	users.Use(middleware("users", true))
+	locked := admin.Group("/locked", middleware("locked", false))
+	locked.GET("/secret", handler("secret"))
+
+	billing := NewRouter(handler("billing fallthrough"))
+	billing.Use(middleware("billing", true))
+	billing.GET("/invoices/:id", handler("invoice"), middleware("invoice route", true))
+	billing.POST("/", handler("charge"))
+	router.Mount("/billing", billing)
+	admin.Mount("/billing", billing)
+
+	Convey("Group", t, func() {
+		Convey("Should add routes under the group's prefix", func() {
+			status, calls := request(router, "GET", "/admin")
+			So(status, ShouldEqual, 200)
+			So(calls, ShouldEqual, "router admin dashboard:")
+		})
+
+		Convey("Should nest groups and run their middleware in order", func() {
+			_, calls := request(router, "GET", "/admin/users/42")
+			So(calls, ShouldEqual, "router admin users route user:42")
+		})
+
+		Convey("Should stop when group middleware returns false", func() {
+			status, calls := request(router, "GET", "/admin/locked/secret")
+			So(status, ShouldEqual, 403)
+			So(calls, ShouldEqual, "router admin locked")
+		})
+
+		Convey("Should not add routes without the prefix", func() {
+			status, _ := request(router, "GET", "/users/42")
+			So(status, ShouldEqual, 404)
+		})
+
+		Convey("Should require the prefix to start with a /", func() {
+			So(func() { router.Group("admin") }, ShouldPanic)
+		})
+
+		Convey("Should handle requests with the Router the group was made from", func() {
+			_, calls := request(users, "GET", "/admin/users/42")
+			So(calls, ShouldEqual, "router admin users route user:42")
+		})
+	})
+
+	Convey("Mount", t, func() {
+		Convey("Should add the other router's routes and middleware under the prefix", func() {
+			_, calls := request(router, "GET", "/billing/invoices/7")
+			So(calls, ShouldEqual, "router billing invoice route invoice:7")
+
+			status, calls := request(router, "POST", "/billing")
+			So(status, ShouldEqual, 200)
+			So(calls, ShouldEqual, "router billing charge:")
+		})
+
+		Convey("Should mount routers in a group", func() {
+			_, calls := request(router, "GET", "/admin/billing/invoices/7")
+			So(calls, ShouldEqual, "router admin billing invoice route invoice:7")
+		})
+
+		Convey("Should not use the other router's fall through handler", func() {
+			_, calls := request(router, "GET", "/billing/missing")
+			So(calls, ShouldEqual, "router fallthrough")
+		})
+
+		Convey("Should not mount groups or routers without routes", func() {
+			So(func() { router.Mount("/locked", locked) }, ShouldPanicWith, "Mount can't mount a group, mount the Router it was made from.")
+			So(func() { router.Mount("/empty", &Router{}) }, ShouldPanicWith, "Mount needs a Router made with NewRouter().")
+		})
+	})
+
+	Convey("joinRoutePath", t, func() {
+		So(joinRoutePath("/admin", "/users"), ShouldEqual, "/admin/users")
+		So(joinRoutePath("/admin/", "/"), ShouldEqual, "/admin")
+		So(joinRoutePath("/", "/users"), ShouldEqual, "/users")
+		So(joinRoutePath("/", "/"), ShouldEqual, "/")
+	})
+}
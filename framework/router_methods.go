@@ -0,0 +1,102 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// routeFor returns the route to handle a request method for the node matched by the path, or nil if no route
+// matched the path. When the node has no route for the method, HEAD requests are handled by the GET route (with
+// the body removed) and other methods are answered with the Allow header, see OptionsHandler and
+// MethodNotAllowedHandler. Adding a route for the method overrides this.
+func (r *Router) routeFor(n *node, method string) *route {
+	if len(n.methods) == 0 {
+		return nil
+	}
+	if rt := n.methods[method]; rt != nil {
+		return rt
+	}
+
+	if method == head {
+		if rt := n.methods[get]; rt != nil {
+			return &route{handler: headHandler(rt.handler), middleware: rt.middleware}
+		}
+	}
+
+	handler := r.MethodNotAllowedHandler
+	if handler == nil {
+		handler = methodNotAllowed
+	}
+	if method == options {
+		handler = r.OptionsHandler
+		if handler == nil {
+			handler = noContent
+		}
+	}
+	return &route{handler: allowHandler(allowedMethods(n), handler)}
+}
+
+// allowedMethods returns the methods a node's routes can be requested with, for the Allow header
+func allowedMethods(n *node) string {
+	allowed := map[string]bool{options: true}
+	for method := range n.methods {
+		allowed[method] = true
+	}
+	if allowed[get] {
+		allowed[head] = true
+	}
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// allowHandler returns a handler that sets the Allow header before calling the given handler
+func allowHandler(allow string, handler RouteHandler) RouteHandler {
+	return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		res.SetHeader(HeaderAllow, allow)
+		return handler(ctx, d, req, res, params)
+	}
+}
+
+// headHandler returns a handler for HEAD requests that calls a GET handler and removes the response body
+func headHandler(handler RouteHandler) RouteHandler {
+	return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		err := handler(ctx, d, req, res, params)
+		res.Body = ""
+		res.IsBase64Encoded = false
+		return err
+	}
+}
+
+// methodNotAllowed is the default MethodNotAllowedHandler
+func methodNotAllowed(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+	res.Error(http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+	return nil
+}
+
+// noContent is the default OptionsHandler
+func noContent(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+	res.SetStatus(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRouterMethods(t *testing.T) {
+	fallThrough := false
+	router := NewRouter(func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		fallThrough = true
+		res.StatusCode = 404
+		return nil
+	})
+	get := func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		res.String(200, "widgets")
+		res.SetHeader("X-Widgets", "3")
+		return nil
+	}
+	router.GET("/widgets", get)
+	router.POST("/widgets", get)
+	router.DELETE("/widgets/:id", get)
+	router.OPTIONS("/custom", get)
+
+	request := func(method string, path string) APIGatewayProxyResponse {
+		fallThrough = false
+		res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{HTTPMethod: method, Path: path})
+		return res
+	}
+
+	Convey("Method Not Allowed", t, func() {
+		Convey("Should return a 405 with the Allow header when the path has no route for the method", func() {
+			res := request("PUT", "/widgets")
+			So(res.StatusCode, ShouldEqual, 405)
+			So(res.Headers[HeaderAllow], ShouldEqual, "GET, HEAD, OPTIONS, POST")
+			So(res.Body, ShouldEqual, "Method Not Allowed")
+			So(fallThrough, ShouldBeFalse)
+
+			res = request("GET", "/widgets/1")
+			So(res.Headers[HeaderAllow], ShouldEqual, "DELETE, OPTIONS")
+		})
+
+		Convey("Should still fall through for unknown paths", func() {
+			res := request("PUT", "/gadgets")
+			So(res.StatusCode, ShouldEqual, 404)
+			So(fallThrough, ShouldBeTrue)
+		})
+
+		Convey("Should use the MethodNotAllowedHandler", func() {
+			router.MethodNotAllowedHandler = func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+				res.JSONError(405, errors.New("nope"))
+				return nil
+			}
+			defer func() { router.MethodNotAllowedHandler = nil }()
+			res := request("PUT", "/widgets")
+			So(res.Body, ShouldEqual, `{"error":"nope"}`)
+			So(res.Headers[HeaderAllow], ShouldEqual, "GET, HEAD, OPTIONS, POST")
+		})
+	})
+
+	Convey("HEAD", t, func() {
+		Convey("Should use the GET route without the body", func() {
+			res := request("HEAD", "/widgets")
+			So(res.StatusCode, ShouldEqual, 200)
+			So(res.Headers["X-Widgets"], ShouldEqual, "3")
+			So(res.Body, ShouldBeEmpty)
+		})
+	})
+
+	Convey("OPTIONS", t, func() {
+		Convey("Should answer with the allowed methods", func() {
+			res := request("OPTIONS", "/widgets")
+			So(res.StatusCode, ShouldEqual, 204)
+			So(res.Headers[HeaderAllow], ShouldEqual, "GET, HEAD, OPTIONS, POST")
+		})
+
+		Convey("Should use an OPTIONS route when there is one", func() {
+			res := request("OPTIONS", "/custom")
+			So(res.Body, ShouldEqual, "widgets")
+		})
+
+		Convey("Should use the OptionsHandler", func() {
+			router.OptionsHandler = func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+				res.SetStatus(200)
+				return nil
+			}
+			defer func() { router.OptionsHandler = nil }()
+			So(request("OPTIONS", "/widgets").StatusCode, ShouldEqual, 200)
+		})
+	})
+}
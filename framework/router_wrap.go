@@ -0,0 +1,49 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"net/url"
+)
+
+// Wrap will add HandlerMiddleware to the Router that wraps the handler of every request, including the fall through
+// handler. On a group, it wraps the group's routes. HandlerMiddleware runs after all Middleware has returned true,
+// with the Router's wrapping a group's, which wraps a route's (see WrapHandler()). The first given is the outermost.
+func (r *Router) Wrap(middleware ...HandlerMiddleware) {
+	r.wrappers = append(r.wrappers, middleware...)
+}
+
+// WrapHandler returns the handler wrapped by HandlerMiddleware, for use with a single route.
+// The first given is the outermost, so it runs first and sees the response last.
+func WrapHandler(handler RouteHandler, middleware ...HandlerMiddleware) RouteHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// wrap returns the handler wrapped by the Router's HandlerMiddleware
+func (r *Router) wrap(handler RouteHandler) RouteHandler {
+	return WrapHandler(handler, r.wrappers...)
+}
+
+// groupHandler returns a handler that calls the given handler wrapped by the Router's HandlerMiddleware. Like
+// groupMiddleware(), it's looked up for each request, so HandlerMiddleware added with Wrap() after routes still runs.
+func (r *Router) groupHandler(handler RouteHandler) RouteHandler {
+	return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		return r.wrap(handler)(ctx, d, req, res, params)
+	}
+}
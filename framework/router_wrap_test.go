@@ -0,0 +1,144 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRouterWrap(t *testing.T) {
+	var calls []string
+	record := func(name string) HandlerMiddleware {
+		return func(next RouteHandler) RouteHandler {
+			return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, d, req, res, params)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	recovered := func(next RouteHandler) RouteHandler {
+		return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+			err := next(ctx, d, req, res, params)
+			if err != nil {
+				res.String(503, "recovered: "+err.Error())
+			}
+			return nil
+		}
+	}
+	handler := func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		calls = append(calls, "handler")
+		res.String(200, "ok")
+		return nil
+	}
+	failing := func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		return errors.New("failed")
+	}
+	middleware := func(name string) Middleware {
+		return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) bool {
+			calls = append(calls, name)
+			return true
+		}
+	}
+
+	router := NewRouter(func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+		calls = append(calls, "fall through")
+		res.StatusCode = 404
+		return nil
+	})
+	router.Use(middleware("router middleware"))
+	router.GET("/widgets", WrapHandler(handler, record("route")), middleware("route middleware"))
+	router.GET("/fail", failing)
+	router.GET("/recover", WrapHandler(failing, recovered))
+	admin := router.Group("/admin")
+	admin.GET("/users", handler)
+	// Added after the group's routes, still wraps them
+	admin.Wrap(record("group"))
+	router.Wrap(record("router"))
+
+	mounted := NewRouter(nil)
+	mounted.Wrap(record("mounted"))
+	mounted.GET("/", handler)
+	router.Mount("/v2", mounted)
+
+	request := func(path string) APIGatewayProxyResponse {
+		calls = nil
+		res, _ := router.LambdaHandler(context.Background(), &HandlerDependencies{}, APIGatewayProxyRequest{HTTPMethod: "GET", Path: path})
+		return res
+	}
+
+	Convey("Wrap", t, func() {
+		Convey("Should wrap route handlers after the middleware, outermost first", func() {
+			res := request("/widgets")
+			So(res.StatusCode, ShouldEqual, 200)
+			So(calls, ShouldResemble, []string{"router middleware", "route middleware", "router before", "route before", "handler", "route after", "router after"})
+		})
+
+		Convey("Should wrap group routes with the group's HandlerMiddleware", func() {
+			request("/admin/users")
+			So(calls, ShouldResemble, []string{"router middleware", "router before", "group before", "handler", "group after", "router after"})
+		})
+
+		Convey("Should wrap mounted routes with the mounted Router's HandlerMiddleware", func() {
+			request("/v2")
+			So(calls, ShouldResemble, []string{"router middleware", "router before", "mounted before", "handler", "mounted after", "router after"})
+		})
+
+		Convey("Should wrap the fall through handler", func() {
+			res := request("/missing")
+			So(res.StatusCode, ShouldEqual, 404)
+			So(calls, ShouldResemble, []string{"router middleware", "router before", "fall through", "router after"})
+		})
+
+		Convey("Should let HandlerMiddleware handle errors returned by the handler", func() {
+			res := request("/recover")
+			So(res.StatusCode, ShouldEqual, 503)
+			So(res.Body, ShouldEqual, "recovered: failed")
+
+			res = request("/fail")
+			So(res.StatusCode, ShouldEqual, 500)
+		})
+
+		Convey("Should run the same way with the local gateway", func() {
+			calls = nil
+			rw := httptest.NewRecorder()
+			gatewayHandler(*router).ServeHTTP(rw, httptest.NewRequest("GET", "/admin/users", nil))
+			So(rw.Code, ShouldEqual, 200)
+			So(calls, ShouldResemble, []string{"router middleware", "router before", "group before", "handler", "group after", "router after"})
+
+			rw = httptest.NewRecorder()
+			gatewayHandler(*router).ServeHTTP(rw, httptest.NewRequest("GET", "/fail", nil))
+			So(rw.Code, ShouldEqual, 500)
+			So(strings.TrimSpace(rw.Body.String()), ShouldContainSubstring, "failed")
+		})
+	})
+
+	Convey("WrapHandler", t, func() {
+		Convey("Should return the handler when there is no HandlerMiddleware", func() {
+			calls = nil
+			res := APIGatewayProxyResponse{}
+			WrapHandler(handler)(context.Background(), &HandlerDependencies{}, &APIGatewayProxyRequest{}, &res, url.Values{})
+			So(calls, ShouldResemble, []string{"handler"})
+		})
+	})
+}
@@ -16,76 +16,115 @@ package framework
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	lambdaSDK "github.com/aws/aws-sdk-go/service/lambda"
 )
 
 // RPCRouter struct provides an interface to handle remote procedures (other Lambdas invoking the one listening via AWS SDK)
 type RPCRouter struct {
-	handlers map[string]RPCHandler
-	Tracer   TraceStrategy
+	handlers  map[string]RPCHandler
+	contracts map[string]ProcedureContract
+	Tracer    TraceStrategy
+	// JSONRPCDescribe allows the RPCDescribe procedure to be called with JSON-RPC (see Router.JSONRPC()), making the
+	// catalogue of procedures public. Other Lambdas can always call it.
+	JSONRPCDescribe bool
 }
 
 // RPCHandler is similar to and other router/handler but it returns a map[string]interface{} in addition to an error
 type RPCHandler func(context.Context, *HandlerDependencies, map[string]interface{}) (map[string]interface{}, error)
 
+// rpcAsyncKey marks asynchronous calls (see RPCAsync()) in the message
+const rpcAsyncKey = "_rpcAsync"
+
 // LambdaHandler is a native AWS Lambda Go handler function. Handles a remote procedure call (invocation via SDK with a special event format).
+// Errors returned by handlers are sent back to the caller as an error envelope, which the caller returns as an *RPCError.
+// Nobody reads the response of an asynchronous call, so the error is returned instead. This fails the invocation,
+// so Lambda retries it and then sends it to the function's dead letter queue or on-failure destination (if any).
 func (r *RPCRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
-	var err error
 	var response map[string]interface{}
-
-	handled := false
-	procedureName := ""
-	if name, ok := evt["_rpcName"]; ok {
-		procedureName = name.(string)
+	procedureName, _ := evt["_rpcName"].(string)
+	async, _ := evt[rpcAsyncKey].(bool)
+
+	// If there's a _rpcName, use the registered handler if it exists.
+	// Otherwise, use the catch all (router "fallthrough" equivalent) handler.
+	// The application can inspect the map and make a decision on what to do, if anything.
+	handler, ok := r.handlerFor(procedureName)
+	// Trace (default is to use XRay)
+	// Annotations can be searched in XRay.
+	// For example: annotation.RPCName = "myProcedure"
+	r.Tracer.Annotations = map[string]interface{}{
+		"RPCName": procedureName,
 	}
-
-	if r.handlers != nil {
-		// If there's a _rpcName, use the registered handler if it exists.
-		if handler, ok := r.handlers[procedureName]; ok {
-			handled = true
-			// Trace (default is to use XRay)
-			// Annotations can be searched in XRay.
-			// For example: annotation.RPCName = "myProcedure"
-			r.Tracer.Annotations = map[string]interface{}{
-				"RPCName": procedureName,
-			}
-			err = r.Tracer.Capture(ctx, "RPCHandler", func(ctx1 context.Context) error {
-				r.Tracer.AddAnnotations(ctx1)
-				r.Tracer.AddMetadata(ctx1)
-				d.Tracer = &r.Tracer
-				response, err = handler(ctx1, d, evt)
-				return err
-			})
-		}
-		// Otherwise, use the catch all (router "fallthrough" equivalent) handler.
-		// The application can inspect the map and make a decision on what to do, if anything.
-		// This is optional.
-		if !handled {
-			// It's possible that the RPCRouter wasn't created with NewRPCRouter, so check for this still.
-			if handler, ok := r.handlers["*"]; ok {
-				// Capture the handler (in XRay by default) automatically
-				r.Tracer.Annotations = map[string]interface{}{
-					"RPCName":            procedureName,
-					"FallthroughHandler": true,
-				}
-				err = r.Tracer.Capture(ctx, "RPCHandler", func(ctx1 context.Context) error {
-					r.Tracer.AddAnnotations(ctx1)
-					r.Tracer.AddMetadata(ctx1)
-					d.Tracer = &r.Tracer
-					response, err = handler(ctx1, d, evt)
-					return err
-				})
+	if !ok {
+		r.Tracer.Annotations["FallthroughHandler"] = true
+	}
+	// The caller's trace context and correlation ID are passed along with the event, see RPCClient.Invoke()
+	ctx, d = r.restoreTraceContext(ctx, d, evt)
+	evt = withoutRPCKeys(evt, rpcTraceKey, rpcAsyncKey)
+	// Panics are recovered within the subsegment so it's closed with the error, then the panic continues
+	var panicked interface{}
+	err := r.Tracer.Capture(ctx, "RPCHandler", func(ctx1 context.Context) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked = p
+				err = fmt.Errorf("panic: %v", p)
 			}
+		}()
+		r.Tracer.AddAnnotations(ctx1)
+		r.Tracer.AddMetadata(ctx1)
+		d.Tracer = &r.Tracer
+		response, err = handler(ctx1, d, evt)
+		return err
+	})
+	if panicked != nil {
+		panic(panicked)
+	}
+
+	// Errors are sent back to the caller in an error envelope, rather than failing the invocation
+	if err != nil {
+		log.Println("remote procedure returned an error:", err)
+		if async {
+			return nil, err
 		}
+		return rpcErrorEnvelope(procedureName, err), nil
+	}
+
+	return response, nil
+}
+
+// handlerFor returns the handler for a procedure, or the fall through handler (with false) when there isn't one
+func (r *RPCRouter) handlerFor(procedureName string) (RPCHandler, bool) {
+	if procedureName == RPCDescribe {
+		return r.describe, true
+	}
+	if handler, ok := r.handlers[procedureName]; ok && procedureName != "*" {
+		return handler, true
 	}
+	// It's possible that the RPCRouter wasn't created with NewRPCRouter, so check for this still.
+	if handler, ok := r.handlers["*"]; ok {
+		return handler, false
+	}
+	return func(context.Context, *HandlerDependencies, map[string]interface{}) (map[string]interface{}, error) {
+		return nil, nil
+	}, false
+}
 
-	return response, err
+// withoutRPCKeys returns a copy of the event without the given keys (ie. the trace carrier),
+// so handlers only get the call's parameters
+func withoutRPCKeys(evt map[string]interface{}, keys ...string) map[string]interface{} {
+	skip := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		skip[k] = true
+	}
+	m := make(map[string]interface{}, len(evt))
+	for k, v := range evt {
+		if !skip[k] {
+			m[k] = v
+		}
+	}
+	return m
 }
 
 // Listen will start an RPC listener which acts much like a task handler except that it handles special RPC events instead
@@ -109,45 +148,38 @@ func NewRPCRouter(rootHandler ...RPCHandler) *RPCRouter {
 	}
 }
 
-// Handle will register a handler for a given remote procedure name
-func (r *RPCRouter) Handle(name string, handler RPCHandler) {
+// Handle will register a handler for a given remote procedure name, with an optional contract describing it.
+// Handle will panic if the name is reserved (see RPCDescribe) or if the contract's schemas can't be marshaled to JSON
+// or have a pattern that isn't a valid regular expression.
+func (r *RPCRouter) Handle(name string, handler RPCHandler, contract ...ProcedureContract) {
+	if name == RPCDescribe {
+		panic("procedure name " + name + " is reserved")
+	}
 	if r.handlers == nil {
 		r.handlers = make(map[string]RPCHandler)
 	}
+	if len(contract) > 0 {
+		c, err := contract[0].normalize()
+		if err != nil {
+			panic("invalid contract for " + name + ": " + err.Error())
+		}
+		if r.contracts == nil {
+			r.contracts = make(map[string]ProcedureContract)
+		}
+		r.contracts[name] = c
+		handler, err = contractRPCHandler(name, c, handler)
+		if err != nil {
+			panic("invalid contract for " + name + ": " + err.Error())
+		}
+	}
 	r.handlers[name] = handler
 }
 
-// RPC will make the remote procedure call (invoke another lambda)
-func RPC(functionName string, message map[string]interface{}) (map[string]interface{}, error) {
-	sess, err := session.NewSession()
-	if err != nil {
-		log.Println("could not make remote procedure call, session could not be created")
-		return nil, err
-	}
-
-	// Payload will need JSON bytes
-	jsonBytes, err := json.Marshal(message)
-	if err != nil {
-		log.Println("could not marshal remote procedure call message")
-		return nil, err
-	}
-
-	// region? cross account?
-	svc := lambdaSDK.New(sess)
-	output, err := svc.Invoke(&lambdaSDK.InvokeInput{
-		FunctionName: aws.String(functionName),
-		// JSON bytes, sadly it does not pass just any old byte array. It's going to come in as a map to the handler.
-		// That's a map[string]interface{} from JSON. I saw byte array at first and got excited.
-		Payload: jsonBytes,
-		// Qualifier ... this is an interesting one. We use latest by default...But we also want to work in a circuit breaker
-		// here, so we'll need to set the qualifier at some point.
-	})
-
-	// Unmarshal response.
-	var resp map[string]interface{}
-	if err == nil {
-		err = json.Unmarshal(output.Payload, &resp)
-	}
+// defaultRPCClient is used for untraced remote procedure calls made with RPC()
+var defaultRPCClient = NewRPCClient()
 
-	return resp, err
+// RPC will make the remote procedure call (invoke another lambda). Errors from the remote procedure are returned as an *RPCError.
+// This is an untraced invocation, which means it could be called outside of an event handler.
+func RPC(functionName string, message map[string]interface{}, opts ...RPCOption) (map[string]interface{}, error) {
+	return defaultRPCClient.Invoke(context.Background(), functionName, message, opts...)
 }
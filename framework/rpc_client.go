@@ -0,0 +1,252 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RPCClient makes remote procedure calls (invokes other Lambdas)
+type RPCClient struct {
+	// Transport invokes the functions, by default Lambda functions are invoked (see LambdaRPCTransport)
+	Transport RPCTransport
+	// Resolver maps logical service names to functions (nil disables it, names are then always function names)
+	Resolver *RPCResolver
+	// DeadlineMargin is time kept back from the caller's context deadline, so there's time to handle a failed call (default 100ms)
+	DeadlineMargin time.Duration
+	// Resilience configures circuit breakers, retries and concurrency limits (nil disables them)
+	Resilience *RPCResilience
+	// FanOut configures calls made with RPCAll (by default 10 calls are made at once)
+	FanOut RPCFanOut
+	// Log and Tracer are used to report circuit breaker state changes (Log defaults to framework.Log)
+	Log    *logrus.Logger
+	Tracer *TraceStrategy
+}
+
+// RPCOptions configure a remote procedure call
+type RPCOptions struct {
+	// Async invokes the function with the Event invocation type (fire-and-forget), no response is returned
+	Async bool
+	// Qualifier is a function version or alias, $LATEST is used by default
+	Qualifier string
+	// Region of the function, the session's region is used by default
+	Region string
+	// RoleARN is a role to assume for the call, ie. to invoke a function in another account (use its ARN as the function name)
+	RoleARN string
+	// Timeout for the call, it will be shortened to fit within the context deadline
+	Timeout time.Duration
+	// StageVariables are used to resolve logical service names, see RPCResolver
+	StageVariables map[string]string
+}
+
+// RPCOption sets an option for a remote procedure call
+type RPCOption func(*RPCOptions)
+
+// RPCAsync will invoke the function asynchronously (fire-and-forget) instead of waiting for a response
+func RPCAsync() RPCOption {
+	return func(o *RPCOptions) {
+		o.Async = true
+	}
+}
+
+// RPCQualifier will invoke a specific function version or alias
+func RPCQualifier(qualifier string) RPCOption {
+	return func(o *RPCOptions) {
+		o.Qualifier = qualifier
+	}
+}
+
+// RPCRegion will invoke the function in the given region
+func RPCRegion(region string) RPCOption {
+	return func(o *RPCOptions) {
+		o.Region = region
+	}
+}
+
+// RPCAssumeRole will assume the given role to invoke the function, ie. for calling procedures in other accounts
+func RPCAssumeRole(roleARN string) RPCOption {
+	return func(o *RPCOptions) {
+		o.RoleARN = roleARN
+	}
+}
+
+// RPCTimeout sets a timeout for the call, the context deadline is still respected when shorter
+func RPCTimeout(timeout time.Duration) RPCOption {
+	return func(o *RPCOptions) {
+		o.Timeout = timeout
+	}
+}
+
+// RPCErrorDeadlineExceeded is for calls that were not made because there wasn't enough time left before the context deadline
+const RPCErrorDeadlineExceeded = "DeadlineExceeded"
+
+// defaultRPCDeadlineMargin is the default time kept back from the context deadline
+const defaultRPCDeadlineMargin = 100 * time.Millisecond
+
+// RPCClientOption configures an RPCClient created with NewRPCClient()
+type RPCClientOption func(*RPCClient)
+
+// WithRPCTransport sets the client's transport, ie. WithRPCTransport(LocalRPC) to handle calls in-process
+func WithRPCTransport(transport RPCTransport) RPCClientOption {
+	return func(c *RPCClient) {
+		c.Transport = transport
+	}
+}
+
+// NewRPCClient returns a new RPCClient. Unless set with WithRPCTransport(), the transport is chosen by the
+// AEGIS_RPC_TRANSPORT environment variable, see NewRPCTransport().
+func NewRPCClient(opts ...RPCClientOption) *RPCClient {
+	c := &RPCClient{
+		Resolver:       NewRPCResolver(),
+		DeadlineMargin: defaultRPCDeadlineMargin,
+		Resilience:     NewRPCResilience(),
+		FanOut:         RPCFanOut{Limit: defaultRPCFanOutLimit},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.Transport == nil {
+		c.Transport = NewRPCTransport()
+	}
+	return c
+}
+
+// Invoke will make the remote procedure call (invoke another Lambda) with the given options. The function name can be
+// a logical service name (see RPCResolver). Errors from the remote procedure are returned as an *RPCError.
+// Asynchronous calls return a nil response.
+func (c *RPCClient) Invoke(ctx context.Context, functionName string, message map[string]interface{}, opts ...RPCOption) (map[string]interface{}, error) {
+	var o RPCOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	functionName, o = c.resolve(functionName, o)
+	procedureName, _ := message["_rpcName"].(string)
+
+	// The trace context and correlation ID are passed along so the function called can continue the trace
+	message = withRPCTraceCarrier(message, rpcTraceCarrier(ctx, c.Tracer))
+	// Asynchronous calls are marked so that handler errors fail the invocation, see RPCRouter.LambdaHandler()
+	if o.Async {
+		message = withRPCAsync(message)
+	}
+
+	// Payload will need JSON bytes
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Println("could not marshal remote procedure call message")
+		return nil, err
+	}
+
+	ctx, cancel, err := rpcContext(ctx, o.Timeout, c.DeadlineMargin)
+	if err != nil {
+		if rpcErr, ok := err.(*RPCError); ok {
+			rpcErr.Procedure = procedureName
+		}
+		return nil, err
+	}
+	defer cancel()
+
+	if c.Resilience == nil {
+		return c.invoke(ctx, functionName, procedureName, payload, o)
+	}
+	// Asynchronous calls are never retried, the first may have been queued even if it timed out
+	maxRetries := c.Resilience.MaxRetries
+	if o.Async {
+		maxRetries = 0
+	}
+	return c.Resilience.do(ctx, c, rpcTarget(functionName, o), maxRetries, func(ctx context.Context) (map[string]interface{}, error) {
+		return c.invoke(ctx, functionName, procedureName, payload, o)
+	})
+}
+
+// withRPCAsync returns a copy of the message marked as an asynchronous call, leaving the caller's message unchanged
+func withRPCAsync(message map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(message)+1)
+	for k, v := range message {
+		m[k] = v
+	}
+	m[rpcAsyncKey] = true
+	return m
+}
+
+// rpcTarget identifies the function version or alias (in a region) being called, ie. "aegis_geoip:live@us-east-1"
+func rpcTarget(functionName string, o RPCOptions) string {
+	target := functionName
+	if o.Qualifier != "" {
+		target += ":" + o.Qualifier
+	}
+	if o.Region != "" {
+		target += "@" + o.Region
+	}
+	return target
+}
+
+// invoke calls the function using the client's transport
+func (c *RPCClient) invoke(ctx context.Context, functionName string, procedureName string, payload []byte, o RPCOptions) (map[string]interface{}, error) {
+	if c.Transport == nil {
+		c.Transport = NewRPCTransport()
+	}
+	output, functionError, err := c.Transport.Invoke(ctx, functionName, payload, o)
+	if err != nil {
+		return nil, err
+	}
+	// Asynchronous invocations are only queued, there is no response
+	if o.Async {
+		return nil, nil
+	}
+
+	// Unmarshal response, FunctionErrors and error envelopes are returned as an *RPCError.
+	return rpcResponse(procedureName, functionError, output)
+}
+
+// Call will make a remote procedure call to a procedure registered with RPCRouter, encoding req (a struct or map)
+// as the message and decoding the response into resp (a pointer, or nil to ignore the response)
+func (c *RPCClient) Call(ctx context.Context, functionName string, procedureName string, req interface{}, resp interface{}, opts ...RPCOption) error {
+	message, err := rpcMessage(procedureName, req)
+	if err != nil {
+		return err
+	}
+	response, err := c.Invoke(ctx, functionName, message, opts...)
+	if err != nil {
+		return err
+	}
+	return decodeRPCResponse(procedureName, response, resp)
+}
+
+// rpcContext returns a context for a call with a timeout derived from the context's deadline (less a margin)
+// and the given timeout, whichever is sooner. An *RPCError is returned if the deadline is too close to make the call.
+func rpcContext(ctx context.Context, timeout time.Duration, margin time.Duration) (context.Context, context.CancelFunc, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline) - margin
+		if remaining <= 0 {
+			return nil, nil, &RPCError{Code: RPCErrorDeadlineExceeded, Message: "not enough time left before the context deadline"}
+		}
+		if timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
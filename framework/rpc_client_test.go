@@ -0,0 +1,125 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCClient(t *testing.T) {
+	Convey("RPCOptions", t, func() {
+		Convey("Should set the options", func() {
+			var o RPCOptions
+			for _, opt := range []RPCOption{RPCAsync(), RPCQualifier("live"), RPCRegion("eu-west-1"), RPCAssumeRole("arn:aws:iam::123456789012:role/caller"), RPCTimeout(time.Second)} {
+				opt(&o)
+			}
+			So(o, ShouldResemble, RPCOptions{
+				Async:     true,
+				Qualifier: "live",
+				Region:    "eu-west-1",
+				RoleARN:   "arn:aws:iam::123456789012:role/caller",
+				Timeout:   time.Second,
+			})
+		})
+	})
+
+	Convey("rpcContext", t, func() {
+		Convey("Should not set a deadline without a timeout or context deadline", func() {
+			ctx, cancel, err := rpcContext(context.Background(), 0, defaultRPCDeadlineMargin)
+			So(err, ShouldBeNil)
+			defer cancel()
+			_, ok := ctx.Deadline()
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should derive the timeout from the context deadline, less the margin", func() {
+			parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+			defer cancelParent()
+			ctx, cancel, err := rpcContext(parent, 0, 200*time.Millisecond)
+			So(err, ShouldBeNil)
+			defer cancel()
+			deadline, ok := ctx.Deadline()
+			parentDeadline, _ := parent.Deadline()
+			So(ok, ShouldBeTrue)
+			So(deadline, ShouldHappenBefore, parentDeadline.Add(-150*time.Millisecond))
+		})
+
+		Convey("Should use the timeout when it's sooner than the context deadline", func() {
+			parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+			defer cancelParent()
+			ctx, cancel, err := rpcContext(parent, time.Second, defaultRPCDeadlineMargin)
+			So(err, ShouldBeNil)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+			So(deadline, ShouldHappenBefore, time.Now().Add(2*time.Second))
+		})
+
+		Convey("Should return an *RPCError when there isn't enough time left", func() {
+			parent, cancelParent := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancelParent()
+			_, _, err := rpcContext(parent, 0, defaultRPCDeadlineMargin)
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorDeadlineExceeded)
+		})
+	})
+
+	Convey("Invoke", t, func() {
+		Convey("Should not make the call when the context deadline is too close", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			_, err := NewRPCClient().Invoke(ctx, "aegis_geoip", map[string]interface{}{"_rpcName": "lookup"})
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Procedure, ShouldEqual, "lookup")
+		})
+
+		Convey("Should not retry by default", func() {
+			So(NewRPCClient().Resilience.MaxRetries, ShouldEqual, 0)
+		})
+
+		Convey("Should never retry asynchronous calls", func() {
+			transport := &failingRPCTransport{err: NewRPCError("Unavailable", "try later", true)}
+			client := NewRPCClient()
+			client.Transport = transport
+			client.Resilience.MaxRetries = 2
+			client.Resilience.RetryMaxDelay = time.Millisecond
+
+			_, err := client.Invoke(context.Background(), "aegis_geoip", map[string]interface{}{"_rpcName": "lookup"}, RPCAsync())
+			So(err, ShouldNotBeNil)
+			So(transport.calls, ShouldEqual, 1)
+
+			_, err = client.Invoke(context.Background(), "aegis_geoip", map[string]interface{}{"_rpcName": "lookup"})
+			So(err, ShouldNotBeNil)
+			So(transport.calls, ShouldEqual, 4)
+		})
+	})
+}
+
+// failingRPCTransport counts calls and fails each of them with err
+type failingRPCTransport struct {
+	err   error
+	calls int
+}
+
+func (t *failingRPCTransport) Invoke(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error) {
+	t.calls++
+	return nil, "", t.err
+}
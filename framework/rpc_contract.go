@@ -0,0 +1,125 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+)
+
+// RPCDescribe is the reserved procedure name that returns an RPCRouter's RPCCatalogue
+const RPCDescribe = "__describe"
+
+// ProcedureContract describes a remote procedure. When Input or Output schemas are set, RPCRouter validates
+// the request before calling the handler and the response after it.
+type ProcedureContract struct {
+	Version     string     `json:"version,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Input       JSONSchema `json:"input,omitempty"`
+	Output      JSONSchema `json:"output,omitempty"`
+}
+
+// RPCCatalogue lists the procedures registered with an RPCRouter, returned for the RPCDescribe procedure
+type RPCCatalogue struct {
+	Procedures map[string]ProcedureContract `json:"procedures"`
+}
+
+// normalize returns the contract with its schemas normalized by way of JSON, so they compare the same as decoded events
+func (c ProcedureContract) normalize() (ProcedureContract, error) {
+	for _, schema := range []*JSONSchema{&c.Input, &c.Output} {
+		if *schema == nil {
+			continue
+		}
+		normalized, err := normalizeJSON(*schema)
+		if err != nil {
+			return c, err
+		}
+		m, ok := normalized.(map[string]interface{})
+		if !ok {
+			return c, errors.New("schema must be an object")
+		}
+		*schema = m
+	}
+	return c, nil
+}
+
+// contractRPCHandler wraps a handler, validating the request and response against the contract's schemas.
+// Requests that don't match return an InvalidRequest *RPCError without calling the handler and responses
+// that don't match return an InvalidResponse *RPCError. The schemas are prepared once, an error is returned
+// if they can't be used (ie. a pattern isn't a valid regular expression).
+func contractRPCHandler(name string, contract ProcedureContract, handler RPCHandler) (RPCHandler, error) {
+	var input, output *jsonSchemaValidator
+	var err error
+	if contract.Input != nil {
+		if input, err = newJSONSchemaValidator(contract.Input); err != nil {
+			return nil, errors.New("input " + err.Error())
+		}
+	}
+	if contract.Output != nil {
+		if output, err = newJSONSchemaValidator(contract.Output); err != nil {
+			return nil, errors.New("output " + err.Error())
+		}
+	}
+	if input == nil && output == nil {
+		return handler, nil
+	}
+	return func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		if input != nil {
+			params := make(map[string]interface{}, len(evt))
+			for k, v := range evt {
+				if k != "_rpcName" {
+					params[k] = v
+				}
+			}
+			if err := input.validate(params); err != nil {
+				return nil, &RPCError{Code: RPCErrorInvalidRequest, Message: "request " + err.Error(), Procedure: name}
+			}
+		}
+
+		response, err := handler(ctx, d, evt)
+		if err != nil || output == nil {
+			return response, err
+		}
+		// A nil response is returned as an empty object, so validate it as one
+		var out interface{} = response
+		if response == nil {
+			out = map[string]interface{}{}
+		}
+		if err := output.validate(out); err != nil {
+			return nil, &RPCError{Code: RPCErrorInvalidResponse, Message: "response " + err.Error(), Procedure: name}
+		}
+		return response, nil
+	}, nil
+}
+
+// describe returns the router's catalogue of procedures (all but the fall through handler) with their contracts
+func (r *RPCRouter) describe(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+	catalogue := RPCCatalogue{Procedures: map[string]ProcedureContract{}}
+	for name := range r.handlers {
+		if name != "*" {
+			catalogue.Procedures[name] = r.contracts[name]
+		}
+	}
+	return encodeRPCResponse(catalogue)
+}
+
+// Describe will return the catalogue of procedures registered with the RPCRouter listening in the given function
+func (c *RPCClient) Describe(ctx context.Context, functionName string, opts ...RPCOption) (*RPCCatalogue, error) {
+	catalogue := &RPCCatalogue{}
+	if err := c.Call(ctx, functionName, RPCDescribe, nil, catalogue, opts...); err != nil {
+		return nil, err
+	}
+	return catalogue, nil
+}
@@ -0,0 +1,109 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCContract(t *testing.T) {
+	country := "US"
+	testRouter := NewRPCRouter()
+	testRouter.Procedure("lookup", func(ctx context.Context, d *HandlerDependencies, req testLookupRequest) (*testLookupResponse, error) {
+		return &testLookupResponse{Country: country}, nil
+	}, ProcedureContract{
+		Version:     "1.2.0",
+		Description: "Looks up the country for an IP address",
+		Input: MustParseJSONSchema(`{
+			"type": "object",
+			"required": ["ipAddress"],
+			"properties": {"ipAddress": {"type": "string", "pattern": "^[0-9.]+$"}},
+			"additionalProperties": false
+		}`),
+		Output: JSONSchema{
+			"type":     "object",
+			"required": []string{"country"},
+			"properties": map[string]interface{}{
+				"country": map[string]interface{}{"type": "string", "minLength": 2, "maxLength": 2},
+			},
+		},
+	})
+	testRouter.Handle("ping", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"pong": true}, nil
+	})
+
+	call := func(evt map[string]interface{}) map[string]interface{} {
+		resp, _ := testRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, evt)
+		return resp
+	}
+
+	Convey("Procedure contracts", t, func() {
+		Convey("Should call the handler when the request and response match", func() {
+			resp := call(map[string]interface{}{"_rpcName": "lookup", "ipAddress": "1.2.3.4"})
+			So(resp, ShouldResemble, map[string]interface{}{"country": "US"})
+		})
+
+		Convey("Should return an InvalidRequest error when the request doesn't match", func() {
+			resp := call(map[string]interface{}{"_rpcName": "lookup", "ipAddress": "localhost", "extra": 1})
+			rpcErr := resp[rpcErrorKey].(*RPCError)
+			So(rpcErr.Code, ShouldEqual, RPCErrorInvalidRequest)
+			So(rpcErr.Message, ShouldContainSubstring, "/extra: is not allowed")
+			So(rpcErr.Message, ShouldContainSubstring, "/ipAddress: must match pattern")
+		})
+
+		Convey("Should return an InvalidResponse error when the response doesn't match", func() {
+			country = "United States"
+			defer func() { country = "US" }()
+			resp := call(map[string]interface{}{"_rpcName": "lookup", "ipAddress": "1.2.3.4"})
+			rpcErr := resp[rpcErrorKey].(*RPCError)
+			So(rpcErr.Code, ShouldEqual, RPCErrorInvalidResponse)
+			So(rpcErr.Message, ShouldContainSubstring, "/country: must be at most 2 characters")
+		})
+
+		Convey("Should not allow the reserved describe procedure name", func() {
+			So(func() { testRouter.Handle(RPCDescribe, testRouter.handlers["ping"]) }, ShouldPanic)
+		})
+
+		Convey("Should not allow a contract with an invalid pattern", func() {
+			contract := ProcedureContract{Input: JSONSchema{"properties": map[string]interface{}{"ipAddress": map[string]interface{}{"pattern": "[0-9"}}}}
+			So(func() { testRouter.Handle("bad", testRouter.handlers["ping"], contract) }, ShouldPanicWith, "invalid contract for bad: input invalid pattern [0-9: error parsing regexp: missing closing ]: `[0-9`")
+		})
+	})
+
+	Convey("Describe", t, func() {
+		Convey("Should return the catalogue of procedures", func() {
+			resp := call(map[string]interface{}{"_rpcName": RPCDescribe})
+			var catalogue RPCCatalogue
+			So(decodeJSONEvent(resp, &catalogue), ShouldBeNil)
+			So(catalogue.Procedures, ShouldHaveLength, 2)
+			So(catalogue.Procedures["lookup"].Version, ShouldEqual, "1.2.0")
+			So(catalogue.Procedures["lookup"].Input["required"], ShouldResemble, []interface{}{"ipAddress"})
+			So(catalogue.Procedures["ping"], ShouldResemble, ProcedureContract{})
+		})
+
+		Convey("Should return the catalogue to an RPCClient", func() {
+			transport := &LocalRPCTransport{}
+			transport.Bind("aegis_geoip", testRouter)
+			c := NewRPCClient()
+			c.Transport = transport
+			catalogue, err := c.Describe(context.Background(), "aegis_geoip")
+			So(err, ShouldBeNil)
+			So(catalogue.Procedures["lookup"].Description, ShouldEqual, "Looks up the country for an IP address")
+		})
+	})
+}
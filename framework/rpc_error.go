@@ -0,0 +1,117 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RPCError is a structured remote procedure call error. When a procedure's handler returns an error, RPCRouter sends
+// it back to the caller in an error envelope (see rpcErrorKey) and the caller gets an *RPCError. Handlers can return
+// an *RPCError themselves to set the code and whether or not the call can be retried.
+type RPCError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+	Procedure string `json:"procedure,omitempty"`
+	// Type is the Lambda error type for FunctionErrors, ie. "errorString" or "Runtime.ExitError"
+	Type string `json:"type,omitempty"`
+}
+
+// Remote procedure call error codes
+const (
+	// RPCErrorInvalidRequest is for requests that could not be decoded for the procedure
+	RPCErrorInvalidRequest = "InvalidRequest"
+	// RPCErrorInvalidResponse is for responses that could not be encoded or decoded
+	RPCErrorInvalidResponse = "InvalidResponse"
+	// RPCErrorInternal is for errors returned by procedure handlers that aren't an *RPCError
+	RPCErrorInternal = "Internal"
+	// RPCErrorFunction is for Lambda FunctionErrors, ie. the function panicked, timed out or didn't use RPCRouter
+	RPCErrorFunction = "FunctionError"
+)
+
+// rpcErrorKey is the key in the response map that holds the error envelope
+const rpcErrorKey = "_rpcError"
+
+// Error returns the error message, satisfying the error interface
+func (e *RPCError) Error() string {
+	if e.Procedure != "" {
+		return fmt.Sprintf("rpc %s: %s: %s", e.Procedure, e.Code, e.Message)
+	}
+	return fmt.Sprintf("rpc: %s: %s", e.Code, e.Message)
+}
+
+// NewRPCError returns a new *RPCError for handlers to return
+func NewRPCError(code string, message string, retryable bool) *RPCError {
+	return &RPCError{
+		Code:      code,
+		Message:   message,
+		Retryable: retryable,
+	}
+}
+
+// rpcErrorEnvelope returns the response sent back to the caller when a procedure's handler returns an error
+func rpcErrorEnvelope(procedureName string, err error) map[string]interface{} {
+	rpcErr := &RPCError{Code: RPCErrorInternal, Message: err.Error()}
+	var handlerErr *RPCError
+	if errors.As(err, &handlerErr) {
+		e := *handlerErr
+		rpcErr = &e
+	}
+	if rpcErr.Procedure == "" {
+		rpcErr.Procedure = procedureName
+	}
+	return map[string]interface{}{
+		rpcErrorKey: rpcErr,
+	}
+}
+
+// lambdaFunctionError is the payload Lambda returns for a FunctionError
+type lambdaFunctionError struct {
+	ErrorMessage string `json:"errorMessage"`
+	ErrorType    string `json:"errorType"`
+}
+
+// rpcResponse returns the response map from a remote procedure call's payload. Both error envelopes and Lambda
+// FunctionErrors are returned as an *RPCError.
+func rpcResponse(procedureName string, functionError string, payload []byte) (map[string]interface{}, error) {
+	if functionError != "" {
+		var fnErr lambdaFunctionError
+		if err := json.Unmarshal(payload, &fnErr); err != nil || fnErr.ErrorMessage == "" {
+			fnErr.ErrorMessage = functionError + " error: " + string(payload)
+		}
+		return nil, &RPCError{
+			Code:      RPCErrorFunction,
+			Message:   fnErr.ErrorMessage,
+			Procedure: procedureName,
+			Type:      fnErr.ErrorType,
+		}
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return nil, &RPCError{Code: RPCErrorInvalidResponse, Message: err.Error(), Procedure: procedureName}
+	}
+	if envelope, ok := resp[rpcErrorKey].(map[string]interface{}); ok {
+		rpcErr := &RPCError{}
+		if err := decodeJSONEvent(envelope, rpcErr); err != nil {
+			return nil, &RPCError{Code: RPCErrorInvalidResponse, Message: err.Error(), Procedure: procedureName}
+		}
+		return nil, rpcErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,171 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// RPCCall is a single remote procedure call made by RPCAll
+type RPCCall struct {
+	FunctionName string
+	Message      map[string]interface{}
+	Options      []RPCOption
+}
+
+// RPCResult is the response or error of a single RPCCall
+type RPCResult struct {
+	Response map[string]interface{}
+	Err      error
+}
+
+// RPCFanOut configures how RPCAll makes its calls
+type RPCFanOut struct {
+	// Limit is the number of calls made concurrently (0 makes all calls at once)
+	Limit int
+	// FailFast cancels the remaining calls as soon as one fails
+	FailFast bool
+}
+
+// RPCErrorCanceled is for calls that were not made, or were interrupted, because another call failed with FailFast
+const RPCErrorCanceled = "Canceled"
+
+// defaultRPCFanOutLimit is the default number of concurrent calls
+const defaultRPCFanOutLimit = 10
+
+// InvokeAll makes the calls concurrently and returns their results in the same order as the calls. The error is the
+// first error to occur, if any, and each result holds its own error. Calls not started before the context is done,
+// or interrupted by it, have a Canceled (or DeadlineExceeded) *RPCError. When the client has a Tracer, each call is
+// traced as a subsegment.
+func (c *RPCClient) InvokeAll(ctx context.Context, fanOut RPCFanOut, calls ...RPCCall) ([]RPCResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]RPCResult, len(calls))
+	limit := fanOut.Limit
+	if limit < 1 || limit > len(calls) {
+		limit = len(calls)
+	}
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+calls:
+	for i, call := range calls {
+		// Don't start any more calls once the context is done (ie. another call failed with FailFast)
+		if ctx.Err() == nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			for j := i; j < len(calls); j++ {
+				results[j].Err = rpcCanceledError(ctx, calls[j])
+			}
+			break calls
+		}
+
+		i, call := i, call
+		wg.Add(1)
+		invoke := func(ctx1 context.Context) error {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			resp, err := c.Invoke(ctx1, call.FunctionName, call.Message, call.Options...)
+			// Calls interrupted by the context (ie. canceled with FailFast) fail with whatever error the transport
+			// returns, so they get the same error as the calls that were not made. Calls that failed on their own
+			// keep their error.
+			if ctx.Err() != nil && isContextError(err) {
+				err = rpcCanceledError(ctx, call)
+			}
+			results[i] = RPCResult{Response: resp, Err: err}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				if fanOut.FailFast {
+					cancel()
+				}
+			}
+			return err
+		}
+		if c.Tracer != nil {
+			CaptureAsync(ctx, "RPC "+call.FunctionName, invoke)
+		} else {
+			go invoke(ctx)
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		for _, result := range results {
+			if result.Err != nil {
+				return results, result.Err
+			}
+		}
+	}
+	return results, firstErr
+}
+
+// rpcCanceledError returns the error for a call that was not made because the context was done
+func rpcCanceledError(ctx context.Context, call RPCCall) error {
+	procedureName, _ := call.Message["_rpcName"].(string)
+	code := RPCErrorCanceled
+	if ctx.Err() == context.DeadlineExceeded {
+		code = RPCErrorDeadlineExceeded
+	}
+	return &RPCError{Code: code, Message: ctx.Err().Error(), Procedure: procedureName}
+}
+
+// isContextError returns whether or not an error from a call was caused by its context being done,
+// including the RequestCanceled error returned by the AWS SDK
+func isContextError(err error) bool {
+	if e, ok := err.(awserr.Error); ok {
+		if e.Code() == request.CanceledErrorCode {
+			return true
+		}
+		err = e.OrigErr()
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// RPCAll makes untraced remote procedure calls concurrently, see RPCClient.InvokeAll()
+func RPCAll(ctx context.Context, calls ...RPCCall) ([]RPCResult, error) {
+	return defaultRPCClient.InvokeAll(ctx, defaultRPCClient.FanOut, calls...)
+}
+
+// RPCAll makes traced remote procedure calls concurrently, using the RPC client's FanOut settings.
+// The trace context is used if ctx is nil. See RPCClient.InvokeAll()
+func (a *Aegis) RPCAll(ctx context.Context, calls ...RPCCall) ([]RPCResult, error) {
+	if ctx == nil {
+		ctx = a.TraceContext
+	}
+	c := a.rpcClient()
+	return c.InvokeAll(ctx, c.FanOut, calls...)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCAll(t *testing.T) {
+	Convey("InvokeAll", t, func() {
+		Convey("Should not make calls once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			results, err := NewRPCClient().InvokeAll(ctx, RPCFanOut{Limit: 2},
+				RPCCall{FunctionName: "aegis_geoip", Message: map[string]interface{}{"_rpcName": "lookup"}},
+				RPCCall{FunctionName: "aegis_weather", Message: map[string]interface{}{"_rpcName": "forecast"}},
+			)
+			So(results, ShouldHaveLength, 2)
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorCanceled)
+			So(results[0].Err.(*RPCError).Procedure, ShouldEqual, "lookup")
+			So(results[1].Err.(*RPCError).Procedure, ShouldEqual, "forecast")
+		})
+
+		Convey("Should return no results for no calls", func() {
+			results, err := NewRPCClient().InvokeAll(context.Background(), RPCFanOut{})
+			So(err, ShouldBeNil)
+			So(results, ShouldBeEmpty)
+		})
+	})
+}
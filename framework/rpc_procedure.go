@@ -0,0 +1,137 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	contextType             = reflect.TypeOf((*context.Context)(nil)).Elem()
+	handlerDependenciesType = reflect.TypeOf((*HandlerDependencies)(nil))
+	errorType               = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Procedure will register a typed handler for a given remote procedure name. The handler must be a function like:
+// func(context.Context, *HandlerDependencies, Req) (Resp, error)
+// The event is decoded into Req (a struct, pointer to a struct or map) and Resp is encoded as the response,
+// so it must encode to a JSON object. Requests that can't be decoded return an *RPCError without calling the handler.
+// An optional contract validates the event before it's decoded and the response once encoded, see Handle().
+// Procedure will panic if the handler is not a function with this signature.
+func (r *RPCRouter) Procedure(name string, handler interface{}, contract ...ProcedureContract) {
+	r.Handle(name, typedRPCHandler(name, handler), contract...)
+}
+
+// typedRPCHandler wraps a typed procedure handler with an RPCHandler
+func typedRPCHandler(name string, handler interface{}) RPCHandler {
+	fn := reflect.ValueOf(handler)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 3 || t.NumOut() != 2 ||
+		t.In(0) != contextType || t.In(1) != handlerDependenciesType || t.Out(1) != errorType {
+		panic("invalid procedure handler for " + name + ", expected func(context.Context, *HandlerDependencies, Req) (Resp, error)")
+	}
+	reqType := t.In(2)
+
+	return func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+		req, err := decodeRPCRequest(evt, reqType)
+		if err != nil {
+			return nil, &RPCError{Code: RPCErrorInvalidRequest, Message: err.Error(), Procedure: name}
+		}
+
+		out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(d), req})
+		if errOut := out[1].Interface(); errOut != nil {
+			return nil, errOut.(error)
+		}
+
+		response, err := encodeRPCResponse(out[0].Interface())
+		if err != nil {
+			return nil, &RPCError{Code: RPCErrorInvalidResponse, Message: err.Error(), Procedure: name}
+		}
+		return response, nil
+	}
+}
+
+// decodeRPCRequest decodes the event into a new value of the given type, without the procedure name
+func decodeRPCRequest(evt map[string]interface{}, t reflect.Type) (reflect.Value, error) {
+	params := make(map[string]interface{}, len(evt))
+	for k, v := range evt {
+		if k != "_rpcName" {
+			params[k] = v
+		}
+	}
+
+	isPtr := t.Kind() == reflect.Ptr
+	v := reflect.New(t)
+	if isPtr {
+		v = reflect.New(t.Elem())
+	}
+	if err := decodeJSONEvent(params, v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if isPtr {
+		return v, nil
+	}
+	return v.Elem(), nil
+}
+
+// encodeRPCResponse encodes a procedure's response into the map returned to the caller
+func encodeRPCResponse(resp interface{}) (map[string]interface{}, error) {
+	var response map[string]interface{}
+	b, err := json.Marshal(resp)
+	if err == nil {
+		err = json.Unmarshal(b, &response)
+	}
+	return response, err
+}
+
+// rpcMessage returns the message for calling a procedure with the given request (a struct or map)
+func rpcMessage(procedureName string, req interface{}) (map[string]interface{}, error) {
+	message := map[string]interface{}{}
+	if req != nil {
+		b, err := json.Marshal(req)
+		if err == nil {
+			err = json.Unmarshal(b, &message)
+		}
+		if err != nil {
+			return nil, &RPCError{Code: RPCErrorInvalidRequest, Message: err.Error(), Procedure: procedureName}
+		}
+	}
+	message["_rpcName"] = procedureName
+	return message, nil
+}
+
+// decodeRPCResponse decodes the response from a procedure into resp (a pointer)
+func decodeRPCResponse(procedureName string, response map[string]interface{}, resp interface{}) error {
+	if resp == nil {
+		return nil
+	}
+	if err := decodeJSONEvent(response, resp); err != nil {
+		return &RPCError{Code: RPCErrorInvalidResponse, Message: err.Error(), Procedure: procedureName}
+	}
+	return nil
+}
+
+// CallProcedure will make an untraced remote procedure call to a procedure registered with RPCRouter, encoding req
+// (a struct or map) as the message and decoding the response into resp (a pointer, or nil to ignore the response)
+func CallProcedure(functionName string, procedureName string, req interface{}, resp interface{}, opts ...RPCOption) error {
+	return defaultRPCClient.Call(context.Background(), functionName, procedureName, req, resp, opts...)
+}
+
+// CallProcedure makes a traced remote procedure call to a procedure registered with RPCRouter, see CallProcedure()
+func (a *Aegis) CallProcedure(functionName string, procedureName string, req interface{}, resp interface{}, opts ...RPCOption) error {
+	return a.rpcClient().Call(a.TraceContext, functionName, procedureName, req, resp, opts...)
+}
@@ -0,0 +1,292 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	lambdaSDK "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/sirupsen/logrus"
+)
+
+// RPCResilience configures circuit breakers, retries and bulkheads (concurrency limits) for remote procedure calls.
+// Each target (function, qualifier and region) has its own circuit breaker and bulkhead. They are kept by the RPCClient,
+// so they persist across warm invocations.
+type RPCResilience struct {
+	// FailureThreshold is the number of consecutive failures that opens a circuit breaker (0 disables circuit breakers)
+	FailureThreshold int
+	// OpenTimeout is how long a circuit breaker stays open before allowing a trial call through
+	OpenTimeout time.Duration
+	// MaxRetries is the number of times a call is retried (0 disables retries), only retryable errors are retried.
+	// Asynchronous calls are never retried, Lambda already retries them and a timed out call may have been queued.
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the jittered exponential backoff between retries
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// MaxConcurrent is the number of concurrent calls allowed for each target (0 is unlimited).
+	// Calls wait for a slot until their context is done.
+	MaxConcurrent int
+
+	mu        sync.Mutex
+	breakers  map[string]*circuitBreaker
+	bulkheads map[string]chan struct{}
+}
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// Resilience error codes
+const (
+	// RPCErrorCircuitOpen is for calls that were not made because the target's circuit breaker is open
+	RPCErrorCircuitOpen = "CircuitOpen"
+	// RPCErrorConcurrencyLimit is for calls that could not get a slot within the target's concurrency limit in time
+	RPCErrorConcurrencyLimit = "ConcurrencyLimit"
+)
+
+// NewRPCResilience returns RPCResilience with default settings. Retries are opt-in, a retried call runs the procedure
+// again so set MaxRetries only when the procedures called are safe to repeat.
+func NewRPCResilience() *RPCResilience {
+	return &RPCResilience{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		RetryBaseDelay:   50 * time.Millisecond,
+		RetryMaxDelay:    time.Second,
+	}
+}
+
+// circuitBreaker tracks failures for a target
+type circuitBreaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+	// trial is set while the single half-open trial call is in flight
+	trial bool
+}
+
+// breaker returns the circuit breaker for a target
+func (r *RPCResilience) breaker(target string) *circuitBreaker {
+	if r.breakers == nil {
+		r.breakers = make(map[string]*circuitBreaker)
+	}
+	b, ok := r.breakers[target]
+	if !ok {
+		b = &circuitBreaker{state: CircuitClosed}
+		r.breakers[target] = b
+	}
+	return b
+}
+
+// allow returns whether or not a call to the target can be made, along with the breaker's state (and previous state)
+func (r *RPCResilience) allow(target string, now time.Time) (bool, string, string) {
+	if r.FailureThreshold < 1 {
+		return true, CircuitClosed, CircuitClosed
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.breaker(target)
+	previous := b.state
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < r.OpenTimeout {
+			return false, b.state, previous
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return true, b.state, previous
+	case CircuitHalfOpen:
+		// Only one trial call at a time
+		if b.trial {
+			return false, b.state, previous
+		}
+		b.trial = true
+	}
+	return true, b.state, previous
+}
+
+// record records the outcome of a call to the target and returns the breaker's state (and previous state)
+func (r *RPCResilience) record(target string, failed bool, now time.Time) (string, string) {
+	if r.FailureThreshold < 1 {
+		return CircuitClosed, CircuitClosed
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.breaker(target)
+	previous := b.state
+	b.trial = false
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+		return b.state, previous
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= r.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+	return b.state, previous
+}
+
+// CircuitState returns the state of the circuit breaker for a target
+func (r *RPCResilience) CircuitState(target string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.breakers[target]; ok {
+		return b.state
+	}
+	return CircuitClosed
+}
+
+// acquire waits for a slot within the target's concurrency limit, returning a function to release it
+func (r *RPCResilience) acquire(ctx context.Context, target string) (func(), error) {
+	if r.MaxConcurrent < 1 {
+		return func() {}, nil
+	}
+	r.mu.Lock()
+	if r.bulkheads == nil {
+		r.bulkheads = make(map[string]chan struct{})
+	}
+	slots, ok := r.bulkheads[target]
+	if !ok {
+		slots = make(chan struct{}, r.MaxConcurrent)
+		r.bulkheads[target] = slots
+	}
+	r.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, &RPCError{Code: RPCErrorConcurrencyLimit, Message: "timed out waiting for the concurrency limit of " + target, Retryable: true}
+	}
+}
+
+// backoff returns a jittered delay before the given retry attempt (starting at 0)
+func (r *RPCResilience) backoff(attempt int) time.Duration {
+	ceiling := r.RetryBaseDelay << uint(attempt)
+	if ceiling <= 0 || (r.RetryMaxDelay > 0 && ceiling > r.RetryMaxDelay) {
+		ceiling = r.RetryMaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	// "Full jitter" so that many callers retrying at once are spread out
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// isRetryableRPCError returns whether or not an error from a call can be retried
+func isRetryableRPCError(err error) bool {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Retryable
+	}
+	if request.IsErrorRetryable(err) || request.IsErrorThrottle(err) {
+		return true
+	}
+	if e, ok := err.(awserr.Error); ok {
+		return e.Code() == lambdaSDK.ErrCodeServiceException || e.Code() == lambdaSDK.ErrCodeTooManyRequestsException
+	}
+	return false
+}
+
+// isRPCFailure returns whether or not an error from a call counts as a failure of the target for its circuit breaker.
+// Errors returned by a procedure's handler don't count unless they are retryable, the target itself is working.
+func isRPCFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Retryable || rpcErr.Code == RPCErrorFunction
+	}
+	return true
+}
+
+// do makes a call to the target through its circuit breaker and bulkhead, retrying retryable errors up to maxRetries times
+func (r *RPCResilience) do(ctx context.Context, c *RPCClient, target string, maxRetries int, call func(context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	var resp map[string]interface{}
+	var err error
+	for attempt := 0; ; attempt++ {
+		allowed, state, previous := r.allow(target, time.Now())
+		c.circuitStateChanged(ctx, target, state, previous)
+		if !allowed {
+			// A retry that finds the breaker open returns the error from the call that opened it
+			if attempt > 0 {
+				return resp, err
+			}
+			return nil, &RPCError{Code: RPCErrorCircuitOpen, Message: "circuit breaker is open for " + target}
+		}
+
+		release, acquireErr := r.acquire(ctx, target)
+		if acquireErr != nil {
+			// Not a failure of the target, but the trial call (if this was one) was not made
+			r.mu.Lock()
+			r.breaker(target).trial = false
+			r.mu.Unlock()
+			return nil, acquireErr
+		}
+		resp, err = call(ctx)
+		release()
+
+		state, previous = r.record(target, isRPCFailure(err), time.Now())
+		c.circuitStateChanged(ctx, target, state, previous)
+
+		if err == nil || attempt >= maxRetries || !isRetryableRPCError(err) {
+			return resp, err
+		}
+		select {
+		case <-time.After(r.backoff(attempt)):
+		case <-ctx.Done():
+			return resp, err
+		}
+	}
+}
+
+// circuitStateChanged logs circuit breaker state changes and adds them to the trace as annotations
+func (c *RPCClient) circuitStateChanged(ctx context.Context, target string, state string, previous string) {
+	if state == previous {
+		return
+	}
+	logger := c.Log
+	if logger == nil {
+		logger = Log
+	}
+	logger.WithFields(logrus.Fields{
+		"rpcTarget":            target,
+		"circuitState":         state,
+		"previousCircuitState": previous,
+	}).Warn("RPC circuit breaker state changed")
+
+	if c.Tracer != nil {
+		// The tracer is shared with the function's other handlers, so its annotations are left alone
+		breakerTrace := &TraceStrategy{Annotations: map[string]interface{}{
+			"RPCTarget":       target,
+			"RPCCircuitState": state,
+		}}
+		c.Tracer.Capture(ctx, "RPCCircuitBreaker", func(ctx1 context.Context) error {
+			breakerTrace.AddAnnotations(ctx1)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCResilience(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	client := &RPCClient{Log: logger}
+	newResilience := func() *RPCResilience {
+		r := NewRPCResilience()
+		r.FailureThreshold = 2
+		r.OpenTimeout = time.Minute
+		r.MaxRetries = 2
+		r.RetryBaseDelay = time.Millisecond
+		r.RetryMaxDelay = 2 * time.Millisecond
+		return r
+	}
+	unavailable := NewRPCError("Unavailable", "try later", true)
+
+	Convey("Retries", t, func() {
+		Convey("Should retry retryable errors", func() {
+			r := newResilience()
+			r.FailureThreshold = 10
+			calls := 0
+			resp, err := r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+				calls++
+				if calls < 3 {
+					return nil, unavailable
+				}
+				return map[string]interface{}{"country": "US"}, nil
+			})
+			So(err, ShouldBeNil)
+			So(resp["country"], ShouldEqual, "US")
+			So(calls, ShouldEqual, 3)
+		})
+
+		Convey("Should not retry other errors", func() {
+			r := newResilience()
+			calls := 0
+			_, err := r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+				calls++
+				return nil, NewRPCError(RPCErrorInvalidRequest, "bad request", false)
+			})
+			So(err, ShouldNotBeNil)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("Should keep the backoff within the maximum delay", func() {
+			r := newResilience()
+			for attempt := 0; attempt < 10; attempt++ {
+				So(r.backoff(attempt), ShouldBeLessThanOrEqualTo, r.RetryMaxDelay)
+			}
+		})
+	})
+
+	Convey("Circuit breaker", t, func() {
+		Convey("Should open after consecutive failures and fail fast", func() {
+			r := newResilience()
+			r.MaxRetries = 0
+			calls := 0
+			failing := func(context.Context) (map[string]interface{}, error) {
+				calls++
+				return nil, errors.New("connection reset")
+			}
+			r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, failing)
+			r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, failing)
+			So(r.CircuitState("aegis_geoip"), ShouldEqual, CircuitOpen)
+
+			_, err := r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, failing)
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorCircuitOpen)
+			So(calls, ShouldEqual, 2)
+			So(r.CircuitState("aegis_other"), ShouldEqual, CircuitClosed)
+		})
+
+		Convey("Should return the last error when a retry finds the breaker open", func() {
+			r := newResilience()
+			calls := 0
+			_, err := r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+				calls++
+				return nil, unavailable
+			})
+			So(err, ShouldEqual, unavailable)
+			So(calls, ShouldEqual, 2)
+			So(r.CircuitState("aegis_geoip"), ShouldEqual, CircuitOpen)
+		})
+
+		Convey("Should not change the tracer's annotations when the state changes", func() {
+			r := newResilience()
+			r.MaxRetries = 0
+			tracer := &TraceStrategy{Annotations: map[string]interface{}{"CorrelationID": "abc"}}
+			tracedClient := &RPCClient{Log: logger, Tracer: tracer}
+			for i := 0; i < 2; i++ {
+				r.do(context.Background(), tracedClient, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+					return nil, unavailable
+				})
+			}
+			So(r.CircuitState("aegis_geoip"), ShouldEqual, CircuitOpen)
+			So(tracer.Annotations, ShouldResemble, map[string]interface{}{"CorrelationID": "abc"})
+		})
+
+		Convey("Should not count handler errors as failures", func() {
+			r := newResilience()
+			for i := 0; i < 3; i++ {
+				r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+					return nil, NewRPCError(RPCErrorInternal, "not found", false)
+				})
+			}
+			So(r.CircuitState("aegis_geoip"), ShouldEqual, CircuitClosed)
+		})
+
+		Convey("Should allow a single trial call once the open timeout has passed", func() {
+			r := newResilience()
+			now := time.Now()
+			r.record("aegis_geoip", true, now)
+			r.record("aegis_geoip", true, now)
+			allowed, _, _ := r.allow("aegis_geoip", now.Add(time.Second))
+			So(allowed, ShouldBeFalse)
+
+			allowed, state, previous := r.allow("aegis_geoip", now.Add(2*time.Minute))
+			So(allowed, ShouldBeTrue)
+			So(state, ShouldEqual, CircuitHalfOpen)
+			So(previous, ShouldEqual, CircuitOpen)
+			allowed, _, _ = r.allow("aegis_geoip", now.Add(2*time.Minute))
+			So(allowed, ShouldBeFalse)
+
+			state, _ = r.record("aegis_geoip", false, now.Add(2*time.Minute))
+			So(state, ShouldEqual, CircuitClosed)
+		})
+	})
+
+	Convey("Bulkhead", t, func() {
+		Convey("Should limit concurrent calls per target", func() {
+			r := newResilience()
+			r.MaxConcurrent = 2
+			var inFlight, maxInFlight int32
+			var wg sync.WaitGroup
+			for i := 0; i < 6; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.do(context.Background(), client, "aegis_geoip", r.MaxRetries, func(context.Context) (map[string]interface{}, error) {
+						n := atomic.AddInt32(&inFlight, 1)
+						for {
+							m := atomic.LoadInt32(&maxInFlight)
+							if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+								break
+							}
+						}
+						time.Sleep(5 * time.Millisecond)
+						atomic.AddInt32(&inFlight, -1)
+						return nil, nil
+					})
+				}()
+			}
+			wg.Wait()
+			So(atomic.LoadInt32(&maxInFlight), ShouldEqual, 2)
+		})
+
+		Convey("Should return an error when no slot is available before the context is done", func() {
+			r := newResilience()
+			r.MaxConcurrent = 1
+			release, _ := r.acquire(context.Background(), "aegis_geoip")
+			defer release()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+			defer cancel()
+			_, err := r.acquire(ctx, "aegis_geoip")
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorConcurrencyLimit)
+		})
+	})
+
+	Convey("isRetryableRPCError", t, func() {
+		Convey("Should retry throttling and retryable RPC errors only", func() {
+			So(isRetryableRPCError(awserr.New("TooManyRequestsException", "Rate exceeded", nil)), ShouldBeTrue)
+			So(isRetryableRPCError(unavailable), ShouldBeTrue)
+			So(isRetryableRPCError(NewRPCError(RPCErrorFunction, "panic", false)), ShouldBeFalse)
+			So(isRetryableRPCError(errors.New("unknown")), ShouldBeFalse)
+		})
+	})
+
+	Convey("rpcTarget", t, func() {
+		Convey("Should include the qualifier and region", func() {
+			So(rpcTarget("aegis_geoip", RPCOptions{Qualifier: "live", Region: "us-east-1"}), ShouldEqual, "aegis_geoip:live@us-east-1")
+		})
+	})
+}
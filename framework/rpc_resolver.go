@@ -0,0 +1,151 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RPCService is what a logical service name resolves to
+type RPCService struct {
+	FunctionName string `json:"functionName"`
+	// Qualifier is the function alias (or version) to invoke
+	Qualifier string `json:"qualifier,omitempty"`
+	Region    string `json:"region,omitempty"`
+}
+
+// RPCResolver maps logical service names (ie. "geoip") to functions, so callers don't need to reference physical
+// function names that change per stage. Services are resolved in this order:
+//  1. API Gateway stage variables passed with the RPCStageVariables() option, ie. rpc_geoip = "aegis_geoip_dev:live"
+//  2. Environment variables for a single service, ie. AEGIS_RPC_SERVICE_GEOIP = "aegis_geoip:live:us-east-1"
+//  3. The AEGIS_RPC_SERVICES environment variable, a JSON map of services (set by `aegis deploy` from aegis.yaml)
+//  4. Services registered with Register()
+//
+// Names that don't resolve are used as the function name.
+// Services in variables are written as "function[:qualifier[:region]]", a function ARN can also be used.
+type RPCResolver struct {
+	mu       sync.RWMutex
+	services map[string]RPCService
+	// env holds the services from the AEGIS_RPC_SERVICES environment variable
+	env map[string]RPCService
+}
+
+// The environment variable that holds a JSON map of services, and the prefixes of variables for single services
+const (
+	RPCServicesEnvVar        = "AEGIS_RPC_SERVICES"
+	rpcServiceEnvVarPrefix   = "AEGIS_RPC_SERVICE_"
+	rpcServiceStageVarPrefix = "rpc_"
+)
+
+// NewRPCResolver returns a new RPCResolver, loading services from the AEGIS_RPC_SERVICES environment variable
+func NewRPCResolver() *RPCResolver {
+	r := &RPCResolver{}
+	if raw := os.Getenv(RPCServicesEnvVar); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &r.env); err != nil {
+			log.Println("could not read RPC services from "+RPCServicesEnvVar, err)
+		}
+	}
+	return r
+}
+
+// Register will register a function for a logical service name
+func (r *RPCResolver) Register(name string, service RPCService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.services == nil {
+		r.services = make(map[string]RPCService)
+	}
+	r.services[name] = service
+}
+
+// Resolve returns the function for a logical service name, using the given stage variables (optional).
+// False is returned when the name isn't a known service.
+func (r *RPCResolver) Resolve(name string, stageVariables map[string]string) (RPCService, bool) {
+	if spec, ok := stageVariables[rpcServiceStageVarPrefix+name]; ok && spec != "" {
+		return ParseRPCService(spec), true
+	}
+	if spec := os.Getenv(rpcServiceEnvVarPrefix + rpcServiceEnvName(name)); spec != "" {
+		return ParseRPCService(spec), true
+	}
+	if service, ok := r.env[name]; ok && service.FunctionName != "" {
+		return service, true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	service, ok := r.services[name]
+	return service, ok
+}
+
+// rpcServiceEnvName returns the service name as it's used in environment variable names, ie. "geo-ip" is "GEO_IP"
+func rpcServiceEnvName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// ParseRPCService parses a service written as "function[:qualifier[:region]]" or as a function ARN
+// (arn:aws:lambda:region:account:function:name[:qualifier]), in which case the region is taken from the ARN.
+func ParseRPCService(spec string) RPCService {
+	if strings.HasPrefix(spec, "arn:") {
+		parts := strings.Split(spec, ":")
+		service := RPCService{FunctionName: spec}
+		if len(parts) > 3 {
+			service.Region = parts[3]
+		}
+		if len(parts) > 7 {
+			service.FunctionName = strings.Join(parts[:7], ":")
+			service.Qualifier = parts[7]
+		}
+		return service
+	}
+	parts := strings.SplitN(spec, ":", 3)
+	service := RPCService{FunctionName: parts[0]}
+	if len(parts) > 1 {
+		service.Qualifier = parts[1]
+	}
+	if len(parts) > 2 {
+		service.Region = parts[2]
+	}
+	return service
+}
+
+// RPCStageVariables will resolve logical service names using API Gateway stage variables (ie. req.StageVariables),
+// so each stage can call its own copy of a function
+func RPCStageVariables(stageVariables map[string]string) RPCOption {
+	return func(o *RPCOptions) {
+		o.StageVariables = stageVariables
+	}
+}
+
+// resolve returns the function name and options for a call, filling in the qualifier and region
+// from the resolved service unless they were set with options
+func (c *RPCClient) resolve(name string, o RPCOptions) (string, RPCOptions) {
+	if c.Resolver == nil {
+		return name, o
+	}
+	service, ok := c.Resolver.Resolve(name, o.StageVariables)
+	if !ok {
+		return name, o
+	}
+	if o.Qualifier == "" {
+		o.Qualifier = service.Qualifier
+	}
+	if o.Region == "" {
+		o.Region = service.Region
+	}
+	return service.FunctionName, o
+}
@@ -0,0 +1,95 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCResolver(t *testing.T) {
+	Convey("ParseRPCService", t, func() {
+		Convey("Should parse the function, qualifier and region", func() {
+			So(ParseRPCService("aegis_geoip"), ShouldResemble, RPCService{FunctionName: "aegis_geoip"})
+			So(ParseRPCService("aegis_geoip:live:eu-west-1"), ShouldResemble, RPCService{FunctionName: "aegis_geoip", Qualifier: "live", Region: "eu-west-1"})
+		})
+
+		Convey("Should parse function ARNs", func() {
+			So(ParseRPCService("arn:aws:lambda:us-west-2:123456789012:function:aegis_geoip:live"), ShouldResemble, RPCService{
+				FunctionName: "arn:aws:lambda:us-west-2:123456789012:function:aegis_geoip",
+				Qualifier:    "live",
+				Region:       "us-west-2",
+			})
+		})
+	})
+
+	Convey("Resolve", t, func() {
+		os.Setenv(RPCServicesEnvVar, `{"geoip":{"functionName":"aegis_geoip_prod","qualifier":"live"},"weather":{"functionName":"aegis_weather"}}`)
+		os.Setenv("AEGIS_RPC_SERVICE_WEATHER", "aegis_weather_override")
+		defer os.Unsetenv(RPCServicesEnvVar)
+		defer os.Unsetenv("AEGIS_RPC_SERVICE_WEATHER")
+		r := NewRPCResolver()
+		r.Register("geoip", RPCService{FunctionName: "aegis_geoip_registered"})
+		r.Register("billing", RPCService{FunctionName: "aegis_billing"})
+
+		Convey("Should prefer stage variables", func() {
+			service, ok := r.Resolve("geoip", map[string]string{"rpc_geoip": "aegis_geoip_dev:dev"})
+			So(ok, ShouldBeTrue)
+			So(service, ShouldResemble, RPCService{FunctionName: "aegis_geoip_dev", Qualifier: "dev"})
+		})
+
+		Convey("Should prefer service environment variables over the services map", func() {
+			service, _ := r.Resolve("weather", nil)
+			So(service.FunctionName, ShouldEqual, "aegis_weather_override")
+		})
+
+		Convey("Should prefer the services map over registered services", func() {
+			service, _ := r.Resolve("geoip", nil)
+			So(service, ShouldResemble, RPCService{FunctionName: "aegis_geoip_prod", Qualifier: "live"})
+			service, _ = r.Resolve("billing", nil)
+			So(service.FunctionName, ShouldEqual, "aegis_billing")
+		})
+
+		Convey("Should not resolve unknown names", func() {
+			_, ok := r.Resolve("aegis_geoip", nil)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should resolve names for the RPCClient, keeping explicit options", func() {
+			c := &RPCClient{Resolver: r}
+			name, o := c.resolve("geoip", RPCOptions{Region: "us-east-1"})
+			So(name, ShouldEqual, "aegis_geoip_prod")
+			So(o.Qualifier, ShouldEqual, "live")
+			So(o.Region, ShouldEqual, "us-east-1")
+		})
+
+		Convey("Should call the resolved function", func() {
+			geoip := NewRPCRouter(func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+				return map[string]interface{}{"handled": true}, nil
+			})
+			transport := &LocalRPCTransport{}
+			transport.Bind("aegis_geoip_dev", geoip)
+			c := NewRPCClient()
+			c.Transport = transport
+			c.Resolver = r
+			resp, err := c.Invoke(context.Background(), "geoip", map[string]interface{}{}, RPCStageVariables(map[string]string{"rpc_geoip": "aegis_geoip_dev"}))
+			So(err, ShouldBeNil)
+			So(resp["handled"], ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,152 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testLookupRequest struct {
+	IPAddress string `json:"ipAddress"`
+}
+
+type testLookupResponse struct {
+	Country string `json:"country"`
+}
+
+func TestRPCProcedure(t *testing.T) {
+	testRouter := NewRPCRouter()
+	testRouter.Procedure("lookup", func(ctx context.Context, d *HandlerDependencies, req testLookupRequest) (*testLookupResponse, error) {
+		if req.IPAddress == "" {
+			return nil, errors.New("missing ip address")
+		}
+		return &testLookupResponse{Country: "US"}, nil
+	})
+
+	Convey("Procedure", t, func() {
+		handler := testRouter.handlers["lookup"]
+
+		Convey("Should decode the request and encode the response", func() {
+			res, err := handler(context.Background(), &HandlerDependencies{}, map[string]interface{}{"_rpcName": "lookup", "ipAddress": "1.2.3.4"})
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, map[string]interface{}{"country": "US"})
+		})
+
+		Convey("Should return a structured error when the request can't be decoded", func() {
+			_, err := handler(context.Background(), &HandlerDependencies{}, map[string]interface{}{"_rpcName": "lookup", "ipAddress": 1234})
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorInvalidRequest)
+			So(rpcErr.Procedure, ShouldEqual, "lookup")
+		})
+
+		Convey("Should return the handler's error", func() {
+			_, err := handler(context.Background(), &HandlerDependencies{}, map[string]interface{}{"_rpcName": "lookup"})
+			So(err.Error(), ShouldEqual, "missing ip address")
+		})
+
+		Convey("Should panic for handlers with the wrong signature", func() {
+			So(func() {
+				testRouter.Procedure("bad", func(req testLookupRequest) error { return nil })
+			}, ShouldPanic)
+		})
+	})
+
+	Convey("rpcMessage", t, func() {
+		Convey("Should encode the request along with the procedure name", func() {
+			message, err := rpcMessage("lookup", testLookupRequest{IPAddress: "1.2.3.4"})
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, map[string]interface{}{"_rpcName": "lookup", "ipAddress": "1.2.3.4"})
+		})
+	})
+
+	Convey("decodeRPCResponse", t, func() {
+		Convey("Should decode the response into a struct", func() {
+			var resp testLookupResponse
+			So(decodeRPCResponse("lookup", map[string]interface{}{"country": "US"}, &resp), ShouldBeNil)
+			So(resp.Country, ShouldEqual, "US")
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		var received map[string]interface{}
+		testRouter.Handle("fail", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+			received = evt
+			return nil, errors.New("boom")
+		})
+
+		Convey("Should return handler errors in an error envelope", func() {
+			resp, err := testRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, map[string]interface{}{"_rpcName": "fail"})
+			So(err, ShouldBeNil)
+			So(resp, ShouldContainKey, rpcErrorKey)
+		})
+
+		Convey("Should fail asynchronous invocations with the handler's error so Lambda retries them", func() {
+			resp, err := testRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, map[string]interface{}{"_rpcName": "fail", rpcAsyncKey: true})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "boom")
+			So(resp, ShouldBeNil)
+			So(received, ShouldNotContainKey, rpcAsyncKey)
+		})
+
+		Convey("Should mark asynchronous calls in the message", func() {
+			message := map[string]interface{}{"_rpcName": "fail"}
+			So(withRPCAsync(message)[rpcAsyncKey], ShouldEqual, true)
+			So(message, ShouldNotContainKey, rpcAsyncKey)
+		})
+	})
+
+	Convey("rpcErrorEnvelope", t, func() {
+		Convey("Should wrap handler errors", func() {
+			envelope := rpcErrorEnvelope("lookup", errors.New("boom"))
+			So(envelope[rpcErrorKey], ShouldResemble, &RPCError{Code: RPCErrorInternal, Message: "boom", Procedure: "lookup"})
+		})
+
+		Convey("Should keep the code and retryable flag of an *RPCError", func() {
+			envelope := rpcErrorEnvelope("lookup", NewRPCError("Unavailable", "try later", true))
+			So(envelope[rpcErrorKey], ShouldResemble, &RPCError{Code: "Unavailable", Message: "try later", Retryable: true, Procedure: "lookup"})
+		})
+	})
+
+	Convey("rpcResponse", t, func() {
+		Convey("Should return the response map", func() {
+			resp, err := rpcResponse("lookup", "", []byte(`{"country":"US"}`))
+			So(err, ShouldBeNil)
+			So(resp["country"], ShouldEqual, "US")
+		})
+
+		Convey("Should return an error envelope as an *RPCError", func() {
+			_, err := rpcResponse("lookup", "", []byte(`{"_rpcError":{"code":"Unavailable","message":"try later","retryable":true,"procedure":"lookup"}}`))
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, "Unavailable")
+			So(rpcErr.Retryable, ShouldBeTrue)
+		})
+
+		Convey("Should return a FunctionError as an *RPCError", func() {
+			_, err := rpcResponse("lookup", "Unhandled", []byte(`{"errorMessage":"runtime error: invalid memory address","errorType":"runtimeError"}`))
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorFunction)
+			So(rpcErr.Type, ShouldEqual, "runtimeError")
+			So(rpcErr.Message, ShouldEqual, "runtime error: invalid memory address")
+			So(rpcErr.Procedure, ShouldEqual, "lookup")
+		})
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/sirupsen/logrus"
+)
+
+// rpcTraceKey is the key in the message that holds the trace carrier, which has the caller's trace context
+// (see TraceStrategy.Propagate()) and correlation ID. Callers that can't set it can use the Lambda ClientContext's
+// custom values instead.
+const rpcTraceKey = "_rpcTrace"
+
+// CorrelationIDHeader is the trace carrier key for correlation IDs
+const CorrelationIDHeader = "X-Correlation-Id"
+
+// correlationIDKey is the context key for correlation IDs
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context with the given correlation ID, which is passed along with remote procedure calls
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID for the context. When one wasn't set (see WithCorrelationID()) the Lambda
+// request ID is used, so the invocation that began a chain of remote procedure calls identifies it.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(correlationIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return ""
+}
+
+// rpcTraceCarrier returns the trace carrier for a call, holding the trace context and correlation ID
+func rpcTraceCarrier(ctx context.Context, tracer *TraceStrategy) map[string]string {
+	carrier := map[string]string{}
+	if ctx == nil {
+		return carrier
+	}
+	if tracer == nil {
+		tracer = &TraceStrategy{}
+	}
+	tracer.Propagate(ctx, carrier)
+	if id := CorrelationID(ctx); id != "" {
+		carrier[CorrelationIDHeader] = id
+	}
+	return carrier
+}
+
+// withRPCTraceCarrier returns a copy of the message with the trace carrier, leaving the caller's message unchanged
+func withRPCTraceCarrier(message map[string]interface{}, carrier map[string]string) map[string]interface{} {
+	if len(carrier) == 0 {
+		return message
+	}
+	m := make(map[string]interface{}, len(message)+1)
+	for k, v := range message {
+		m[k] = v
+	}
+	m[rpcTraceKey] = carrier
+	return m
+}
+
+// rpcTraceCarrierFrom returns the trace carrier from the event or, if the event has none, the Lambda ClientContext's
+// custom values
+func rpcTraceCarrierFrom(ctx context.Context, evt map[string]interface{}) map[string]string {
+	carrier := map[string]string{}
+	if values, ok := evt[rpcTraceKey].(map[string]interface{}); ok {
+		for k, v := range values {
+			if s, ok := v.(string); ok {
+				carrier[k] = s
+			}
+		}
+		return carrier
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		for k, v := range lc.ClientContext.Custom {
+			carrier[k] = v
+		}
+	}
+	return carrier
+}
+
+// restoreTraceContext restores the caller's trace context and correlation ID for a remote procedure call,
+// returning the context and dependencies to handle it with
+func (r *RPCRouter) restoreTraceContext(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (context.Context, *HandlerDependencies) {
+	carrier := rpcTraceCarrierFrom(ctx, evt)
+	ctx = r.Tracer.Restore(ctx, carrier)
+
+	id := carrier[CorrelationIDHeader]
+	if id != "" {
+		if r.Tracer.Annotations == nil {
+			r.Tracer.Annotations = map[string]interface{}{}
+		}
+		r.Tracer.Annotations["CorrelationID"] = id
+	}
+	return correlate(ctx, d, id)
+}
+
+// correlate returns the context and dependencies to handle a call with the given correlation ID.
+// Log lines written with the returned dependencies' logger include the correlation ID.
+func correlate(ctx context.Context, d *HandlerDependencies, id string) (context.Context, *HandlerDependencies) {
+	if id == "" {
+		return ctx, d
+	}
+	if d != nil && d.Log != nil {
+		// Dependencies can be shared between calls, so they are copied rather than changed
+		correlated := *d
+		correlated.Log = correlatedLogger(d.Log, id)
+		d = &correlated
+	}
+	return WithCorrelationID(ctx, id), d
+}
+
+// correlatedLogger returns a logger like the given one, but which adds a "correlationId" field to every entry
+func correlatedLogger(l *logrus.Logger, id string) *logrus.Logger {
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range l.Hooks {
+		hooks[level] = append([]logrus.Hook{}, levelHooks...)
+	}
+	hooks.Add(correlationHook(id))
+	return &logrus.Logger{
+		Out:       l.Out,
+		Hooks:     hooks,
+		Formatter: l.Formatter,
+		Level:     l.Level,
+	}
+}
+
+// correlationHook is a logrus hook that adds the correlation ID to entries
+type correlationHook string
+
+// Levels returns all levels, so every entry gets the correlation ID
+func (h correlationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the correlation ID to the entry
+func (h correlationHook) Fire(entry *logrus.Entry) error {
+	entry.Data["correlationId"] = string(h)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRPCTrace(t *testing.T) {
+	traceHeader := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+
+	Convey("CorrelationID", t, func() {
+		Convey("Should return the correlation ID set on the context", func() {
+			So(CorrelationID(WithCorrelationID(context.Background(), "abc")), ShouldEqual, "abc")
+		})
+
+		Convey("Should default to the Lambda request ID", func() {
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "request-1"})
+			So(CorrelationID(ctx), ShouldEqual, "request-1")
+			So(CorrelationID(context.Background()), ShouldEqual, "")
+		})
+	})
+
+	Convey("rpcTraceCarrier", t, func() {
+		Convey("Should hold the Lambda trace header and correlation ID", func() {
+			ctx := context.WithValue(WithCorrelationID(context.Background(), "abc"), xray.LambdaTraceHeaderKey, traceHeader)
+			So(rpcTraceCarrier(ctx, nil), ShouldResemble, map[string]string{
+				XRayTraceHeader:     traceHeader,
+				CorrelationIDHeader: "abc",
+			})
+		})
+
+		Convey("Should use the TraceStrategy's Propagator", func() {
+			tracer := &TraceStrategy{Propagator: func(ctx context.Context, carrier map[string]string) {
+				carrier["traceparent"] = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+			}}
+			So(rpcTraceCarrier(context.Background(), tracer), ShouldResemble, map[string]string{
+				"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			})
+		})
+	})
+
+	Convey("RPCRouter", t, func() {
+		var handledCtx context.Context
+		var handledEvt map[string]interface{}
+		var logs bytes.Buffer
+		logger := logrus.New()
+		logger.Out = &logs
+		logger.Formatter = &logrus.JSONFormatter{}
+		router := NewRPCRouter(func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+			handledCtx = ctx
+			handledEvt = evt
+			d.Log.Info("handled")
+			return nil, nil
+		})
+
+		Convey("Should restore the trace context and correlation ID from the message", func() {
+			router.LambdaHandler(context.Background(), &HandlerDependencies{Log: logger}, map[string]interface{}{
+				"_rpcName": "lookup",
+				rpcTraceKey: map[string]interface{}{
+					XRayTraceHeader:     traceHeader,
+					CorrelationIDHeader: "abc",
+				},
+			})
+			So(CorrelationID(handledCtx), ShouldEqual, "abc")
+			So(handledEvt, ShouldNotContainKey, rpcTraceKey)
+			So(router.Tracer.Annotations["CallerTraceID"], ShouldEqual, "1-5759e988-bd862e3fe1be46a994272793")
+			So(router.Tracer.Annotations["CorrelationID"], ShouldEqual, "abc")
+
+			var entry map[string]interface{}
+			So(json.Unmarshal(logs.Bytes(), &entry), ShouldBeNil)
+			So(entry["correlationId"], ShouldEqual, "abc")
+		})
+
+		Convey("Should restore them from the Lambda ClientContext", func() {
+			lc := &lambdacontext.LambdaContext{}
+			lc.ClientContext.Custom = map[string]string{CorrelationIDHeader: "def"}
+			ctx := lambdacontext.NewContext(context.Background(), lc)
+			router.LambdaHandler(ctx, &HandlerDependencies{Log: logger}, map[string]interface{}{"_rpcName": "lookup"})
+			So(CorrelationID(handledCtx), ShouldEqual, "def")
+		})
+
+		Convey("Should use the TraceStrategy's Restorer", func() {
+			router.Tracer.Restorer = func(ctx context.Context, carrier map[string]string) context.Context {
+				return context.WithValue(ctx, correlationIDKey{}, "from "+carrier["traceparent"])
+			}
+			router.LambdaHandler(context.Background(), &HandlerDependencies{Log: logger}, map[string]interface{}{
+				rpcTraceKey: map[string]interface{}{"traceparent": "parent"},
+			})
+			So(CorrelationID(handledCtx), ShouldEqual, "from parent")
+		})
+
+		Convey("Should be passed along by an RPCClient", func() {
+			transport := &LocalRPCTransport{Dependencies: &HandlerDependencies{Log: logger}}
+			transport.Bind("aegis_geoip", router)
+			c := NewRPCClient()
+			c.Transport = transport
+			message := map[string]interface{}{"_rpcName": "lookup"}
+			_, err := c.Invoke(WithCorrelationID(context.Background(), "ghi"), "aegis_geoip", message)
+			So(err, ShouldBeNil)
+			So(CorrelationID(handledCtx), ShouldEqual, "ghi")
+			So(handledEvt, ShouldNotContainKey, rpcTraceKey)
+			So(message, ShouldNotContainKey, rpcTraceKey)
+			So(logs.String(), ShouldContainSubstring, `"correlationId":"ghi"`)
+		})
+	})
+}
@@ -0,0 +1,237 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	lambdaSDK "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// RPCTransport invokes functions for an RPCClient. The payload is the JSON message and the response is the JSON
+// payload returned by the function, along with the Lambda FunctionError (if the function failed).
+type RPCTransport interface {
+	Invoke(ctx context.Context, functionName string, payload []byte, o RPCOptions) (response []byte, functionError string, err error)
+}
+
+// Transports that can be chosen with the AEGIS_RPC_TRANSPORT environment variable
+const (
+	RPCTransportLambda = "lambda"
+	RPCTransportLocal  = "local"
+)
+
+// LocalRPC is the in-process transport used when AEGIS_RPC_TRANSPORT is "local", bind RPCRouters to it with BindLocalRPC()
+var LocalRPC = &LocalRPCTransport{}
+
+// NewRPCTransport returns the transport chosen by the AEGIS_RPC_TRANSPORT environment variable. When it's "local",
+// the shared LocalRPC transport is returned. Otherwise a new LambdaRPCTransport is returned.
+func NewRPCTransport() RPCTransport {
+	if LocalRPCEnabled() {
+		return LocalRPC
+	}
+	return &LambdaRPCTransport{}
+}
+
+// LocalRPCEnabled returns whether or not the local RPC transport was chosen by the AEGIS_RPC_TRANSPORT environment variable
+func LocalRPCEnabled() bool {
+	return strings.EqualFold(os.Getenv("AEGIS_RPC_TRANSPORT"), RPCTransportLocal)
+}
+
+// BindLocalRPC will bind a function name to an RPCRouter on the shared LocalRPC transport
+func BindLocalRPC(functionName string, router *RPCRouter) {
+	LocalRPC.Bind(functionName, router)
+}
+
+// LambdaRPCTransport invokes Lambda functions. Lambda clients are kept for each region and role
+// so they are reused across warm invocations.
+type LambdaRPCTransport struct {
+	// AWSSession is used to create Lambda clients, a new session is created if not set
+	AWSSession      *session.Session
+	AWSClientTracer func(c *client.Client)
+	mu              sync.Mutex
+	clients         map[string]*lambdaSDK.Lambda
+}
+
+// Invoke will invoke the Lambda function
+func (t *LambdaRPCTransport) Invoke(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error) {
+	svc, err := t.lambdaClient(o.Region, o.RoleARN)
+	if err != nil {
+		log.Println("could not make remote procedure call, session could not be created")
+		return nil, "", err
+	}
+
+	input := &lambdaSDK.InvokeInput{
+		FunctionName: aws.String(functionName),
+		// JSON bytes, sadly it does not pass just any old byte array. It's going to come in as a map to the handler.
+		Payload: payload,
+	}
+	if o.Qualifier != "" {
+		input.Qualifier = aws.String(o.Qualifier)
+	}
+	if o.Async {
+		input.InvocationType = aws.String(lambdaSDK.InvocationTypeEvent)
+	}
+
+	output, err := svc.InvokeWithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	return output.Payload, aws.StringValue(output.FunctionError), nil
+}
+
+// lambdaClient returns a Lambda client for the region and role, creating one if needed
+func (t *LambdaRPCTransport) lambdaClient(region string, roleARN string) (*lambdaSDK.Lambda, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := region + "|" + roleARN
+	if svc, ok := t.clients[key]; ok {
+		return svc, nil
+	}
+
+	if t.AWSSession == nil {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		t.AWSSession = sess
+	}
+
+	cfg := aws.NewConfig()
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	if roleARN != "" {
+		cfg = cfg.WithCredentials(stscreds.NewCredentials(t.AWSSession, roleARN))
+	}
+	svc := lambdaSDK.New(t.AWSSession, cfg)
+	if t.AWSClientTracer != nil {
+		t.AWSClientTracer(svc.Client)
+	}
+
+	if t.clients == nil {
+		t.clients = make(map[string]*lambdaSDK.Lambda)
+	}
+	t.clients[key] = svc
+	return svc, nil
+}
+
+// LocalRPCTransport handles remote procedure calls in-process with RPCRouters bound to function names, for local
+// development and tests. Messages and responses are serialized just like they are when invoking Lambda functions,
+// and calls are handled by RPCRouter.LambdaHandler() just like they are by Lambda.
+type LocalRPCTransport struct {
+	// Dependencies are injected into the handlers (optional)
+	Dependencies *HandlerDependencies
+	mu           sync.RWMutex
+	routers      map[string]*RPCRouter
+}
+
+// Bind will bind a function name to an RPCRouter
+func (t *LocalRPCTransport) Bind(functionName string, router *RPCRouter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.routers == nil {
+		t.routers = make(map[string]*RPCRouter)
+	}
+	t.routers[functionName] = router
+}
+
+// Invoke will call the handler of the RPCRouter bound to the function name. Asynchronous calls are handled in a goroutine.
+func (t *LocalRPCTransport) Invoke(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error) {
+	t.mu.RLock()
+	router, ok := t.routers[functionName]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("function %s is not bound to the local RPC transport", functionName)
+	}
+
+	if o.Async {
+		// The caller doesn't wait, so the call can't be tied to its context
+		go t.handle(context.Background(), functionName, router, payload)
+		return nil, "", nil
+	}
+	response, functionError := t.handle(ctx, functionName, router, payload)
+	return response, functionError, nil
+}
+
+// handle calls the RPCRouter's handler for the payload, returning the response payload or the FunctionError
+// (and its payload) like Lambda would
+func (t *LocalRPCTransport) handle(ctx context.Context, functionName string, router *RPCRouter, payload []byte) (response []byte, functionError string) {
+	defer func() {
+		if r := recover(); r != nil {
+			response, _ = json.Marshal(lambdaFunctionError{ErrorMessage: fmt.Sprint(r), ErrorType: "panic"})
+			functionError = "Unhandled"
+		}
+	}()
+
+	var evt map[string]interface{}
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		response, _ = json.Marshal(lambdaFunctionError{ErrorMessage: err.Error(), ErrorType: "UnmarshalTypeError"})
+		return response, "Unhandled"
+	}
+
+	// Unlike Lambda, calls can be handled concurrently. LambdaHandler() changes the router's Tracer and the
+	// dependencies, so each call gets its own copies.
+	d := &HandlerDependencies{Services: &Services{}, Log: Log}
+	if t.Dependencies != nil {
+		deps := *t.Dependencies
+		d = &deps
+	}
+	// Lambda begins a segment for the function before calling its handler, so one is begun for calls made outside
+	// of a segment (ie. from the local gateway) for the handler's subsegments. It ends when the call is handled.
+	if xray.GetSegment(ctx) == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		var seg *xray.Segment
+		ctx, seg = xray.BeginSegment(ctx, functionName)
+		defer func() { seg.Close(nil) }()
+	}
+	r := *router
+	resp, err := r.LambdaHandler(ctx, d, evt)
+	// Only asynchronous calls fail, the errors of other calls are returned in an error envelope
+	if err != nil {
+		response, _ = json.Marshal(lambdaFunctionError{ErrorMessage: err.Error(), ErrorType: lambdaErrorType(err)})
+		return response, "Unhandled"
+	}
+
+	response, err = json.Marshal(resp)
+	if err != nil {
+		response, _ = json.Marshal(lambdaFunctionError{ErrorMessage: err.Error(), ErrorType: "MarshalError"})
+		return response, "Unhandled"
+	}
+	return response, ""
+}
+
+// lambdaErrorType returns the errorType Lambda reports for a handler's error, the name of the error's type
+func lambdaErrorType(err error) string {
+	errorType := reflect.TypeOf(err)
+	if errorType.Kind() == reflect.Ptr {
+		errorType = errorType.Elem()
+	}
+	return errorType.Name()
+}
@@ -0,0 +1,206 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// rpcTransportFunc adapts a function to an RPCTransport
+type rpcTransportFunc func(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error)
+
+func (f rpcTransportFunc) Invoke(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error) {
+	return f(ctx, functionName, payload, o)
+}
+
+func TestLocalRPCTransport(t *testing.T) {
+	var inFlight, maxInFlight int32
+	geoip := NewRPCRouter()
+	geoip.Procedure("lookup", func(ctx context.Context, d *HandlerDependencies, req testLookupRequest) (testLookupResponse, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		switch req.IPAddress {
+		case "":
+			return testLookupResponse{}, NewRPCError(RPCErrorInvalidRequest, "missing ip address", false)
+		case "slow":
+			time.Sleep(20 * time.Millisecond)
+		case "panic":
+			panic("lookup failed")
+		}
+		return testLookupResponse{Country: "US:" + req.IPAddress}, nil
+	})
+
+	transport := &LocalRPCTransport{}
+	transport.Bind("aegis_geoip", geoip)
+	client := NewRPCClient()
+	client.Transport = transport
+	client.Resilience = nil
+
+	Convey("LocalRPCTransport", t, func() {
+		Convey("Should call the bound RPCRouter", func() {
+			var resp testLookupResponse
+			err := client.Call(context.Background(), "aegis_geoip", "lookup", testLookupRequest{IPAddress: "1.2.3.4"}, &resp)
+			So(err, ShouldBeNil)
+			So(resp.Country, ShouldEqual, "US:1.2.3.4")
+		})
+
+		Convey("Should return handler errors as an *RPCError", func() {
+			err := client.Call(context.Background(), "aegis_geoip", "lookup", testLookupRequest{}, nil)
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorInvalidRequest)
+			So(rpcErr.Message, ShouldEqual, "missing ip address")
+		})
+
+		Convey("Should return panics as a FunctionError", func() {
+			err := client.Call(context.Background(), "aegis_geoip", "lookup", testLookupRequest{IPAddress: "panic"}, nil)
+			var rpcErr *RPCError
+			So(errors.As(err, &rpcErr), ShouldBeTrue)
+			So(rpcErr.Code, ShouldEqual, RPCErrorFunction)
+			So(rpcErr.Message, ShouldEqual, "lookup failed")
+		})
+
+		Convey("Should return an error for functions that are not bound", func() {
+			_, err := client.Invoke(context.Background(), "aegis_missing", map[string]interface{}{"_rpcName": "lookup"})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should handle calls with the RPCRouter's LambdaHandler", func() {
+			var traced bool
+			router := NewRPCRouter()
+			router.Handle("traced", func(ctx context.Context, d *HandlerDependencies, evt map[string]interface{}) (map[string]interface{}, error) {
+				// Calls made outside of a segment get one, as they would from Lambda
+				traced = d.Tracer != nil && d.Tracer.Annotations["RPCName"] == "traced" && xray.GetSegment(ctx) != nil
+				return nil, nil
+			})
+			transport.Bind("aegis_traced", router)
+			_, err := client.Invoke(context.Background(), "aegis_traced", map[string]interface{}{"_rpcName": "traced"})
+			So(err, ShouldBeNil)
+			So(traced, ShouldBeTrue)
+			So(transport.Dependencies, ShouldBeNil)
+		})
+
+		Convey("Should fail asynchronous calls when the handler returns an error", func() {
+			response, functionError := transport.handle(context.Background(), "aegis_geoip", geoip, []byte(`{"_rpcName":"lookup","_rpcAsync":true}`))
+			So(functionError, ShouldEqual, "Unhandled")
+			So(string(response), ShouldEqual, `{"errorMessage":"rpc: InvalidRequest: missing ip address","errorType":"RPCError"}`)
+		})
+
+		Convey("Should not wait for asynchronous calls", func() {
+			// Another function is called so the call can't still be in flight when the geoip calls are counted
+			transport.Bind("aegis_async", NewRPCRouter())
+			resp, err := client.Invoke(context.Background(), "aegis_async", map[string]interface{}{"_rpcName": "lookup"}, RPCAsync())
+			So(err, ShouldBeNil)
+			So(resp, ShouldBeNil)
+		})
+	})
+
+	Convey("InvokeAll", t, func() {
+		call := func(ipAddress string) RPCCall {
+			return RPCCall{FunctionName: "aegis_geoip", Message: map[string]interface{}{"_rpcName": "lookup", "ipAddress": ipAddress}}
+		}
+
+		Convey("Should return results in the same order as the calls, within the limit", func() {
+			atomic.StoreInt32(&maxInFlight, 0)
+			results, err := client.InvokeAll(context.Background(), RPCFanOut{Limit: 2}, call("slow"), call("a"), call("slow"), call("b"))
+			So(err, ShouldBeNil)
+			So(results[0].Response["country"], ShouldEqual, "US:slow")
+			So(results[1].Response["country"], ShouldEqual, "US:a")
+			So(results[3].Response["country"], ShouldEqual, "US:b")
+			So(atomic.LoadInt32(&maxInFlight), ShouldBeLessThanOrEqualTo, 2)
+		})
+
+		Convey("Should return each call's error", func() {
+			results, err := client.InvokeAll(context.Background(), RPCFanOut{}, call("a"), call(""))
+			So(err, ShouldNotBeNil)
+			So(results[0].Err, ShouldBeNil)
+			So(results[1].Err, ShouldEqual, err)
+		})
+
+		Convey("Should cancel the remaining calls with FailFast", func() {
+			results, err := client.InvokeAll(context.Background(), RPCFanOut{Limit: 1, FailFast: true}, call(""), call("a"), call("b"))
+			So(err.(*RPCError).Code, ShouldEqual, RPCErrorInvalidRequest)
+			So(results[2].Err.(*RPCError).Code, ShouldEqual, RPCErrorCanceled)
+		})
+
+		Convey("Should return a Canceled error for calls interrupted by FailFast", func() {
+			interrupted := NewRPCClient()
+			interrupted.Resilience = nil
+			interrupted.Transport = rpcTransportFunc(func(ctx context.Context, functionName string, payload []byte, o RPCOptions) ([]byte, string, error) {
+				switch functionName {
+				case "aegis_failing":
+					time.Sleep(5 * time.Millisecond)
+					return nil, "", errors.New("connection reset")
+				case "aegis_unavailable":
+					// Fails on its own after the other call was canceled
+					<-ctx.Done()
+					return nil, "", NewRPCError("Unavailable", "try later", true)
+				}
+				<-ctx.Done()
+				return nil, "", awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+			})
+			results, err := interrupted.InvokeAll(context.Background(), RPCFanOut{FailFast: true},
+				RPCCall{FunctionName: "aegis_geoip", Message: map[string]interface{}{"_rpcName": "lookup"}},
+				RPCCall{FunctionName: "aegis_failing", Message: map[string]interface{}{"_rpcName": "lookup"}},
+				RPCCall{FunctionName: "aegis_unavailable", Message: map[string]interface{}{"_rpcName": "lookup"}},
+			)
+			So(err.Error(), ShouldEqual, "connection reset")
+			So(results[0].Err.(*RPCError).Code, ShouldEqual, RPCErrorCanceled)
+			So(results[0].Err.(*RPCError).Procedure, ShouldEqual, "lookup")
+			So(results[2].Err.(*RPCError).Code, ShouldEqual, "Unavailable")
+		})
+	})
+
+	Convey("NewRPCClient", t, func() {
+		Convey("Should use the transport given with WithRPCTransport", func() {
+			So(NewRPCClient(WithRPCTransport(transport)).Transport, ShouldEqual, transport)
+		})
+
+		Convey("Should use the transport chosen by the environment by default", func() {
+			os.Setenv("AEGIS_RPC_TRANSPORT", "local")
+			defer os.Unsetenv("AEGIS_RPC_TRANSPORT")
+			So(NewRPCClient().Transport, ShouldEqual, LocalRPC)
+		})
+	})
+
+	Convey("NewRPCTransport", t, func() {
+		Convey("Should use the local transport when set by the environment", func() {
+			os.Setenv("AEGIS_RPC_TRANSPORT", "local")
+			defer os.Unsetenv("AEGIS_RPC_TRANSPORT")
+			So(NewRPCTransport(), ShouldEqual, LocalRPC)
+		})
+
+		Convey("Should use Lambda by default", func() {
+			_, ok := NewRPCTransport().(*LambdaRPCTransport)
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
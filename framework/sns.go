@@ -0,0 +1,204 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/gobwas/glob"
+)
+
+// SNSRouter struct provides an interface to handle SNS notifications (routers can route by topic ARN, subject or message attribute)
+// https://docs.aws.amazon.com/lambda/latest/dg/with-sns.html
+type SNSRouter struct {
+	topicRoutes     []snsRoute
+	subjectRoutes   []snsRoute
+	attributeRoutes []snsRoute
+	rootHandler     SNSHandler
+	Tracer          TraceStrategy
+}
+
+// SNSHandler handles a single routed SNS notification record
+type SNSHandler func(context.Context, *HandlerDependencies, *SNSEventRecord) error
+
+// snsRoute holds a handler along with what it matches. Globs are compiled once upon registration.
+type snsRoute struct {
+	match     string
+	matcher   glob.Glob
+	attribute string
+	handler   SNSHandler
+}
+
+var (
+	// ErrNotS3Notification is returned when an SNS message does not contain an S3 event notification
+	ErrNotS3Notification = errors.New("SNS message is not an S3 event notification")
+)
+
+// LambdaHandler handles SNS events. Every record is handled and the first error returned by a handler is returned.
+func (r *SNSRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt SNSEvent) error {
+	var err error
+
+	// SNS only ever sends one record per invocation, but the event format allows for more.
+	for i := range evt.Records {
+		record := SNSEventRecord(evt.Records[i])
+
+		handler, route, ok := r.handlerFor(&record)
+		r.Tracer.Annotations = map[string]interface{}{
+			"SNSTopicArn":  record.SNS.TopicArn,
+			"SNSMessageID": record.SNS.MessageID,
+			"SNSSubject":   record.SNS.Subject,
+			"SNSRoute":     route,
+		}
+		if !ok {
+			log.Println("using default fall through handler")
+			r.Tracer.Annotations["FallthroughHandler"] = true
+		}
+
+		handlerErr := r.Tracer.Capture(ctx, "SNSHandler", func(ctx1 context.Context) error {
+			r.Tracer.AddAnnotations(ctx1)
+			r.Tracer.AddMetadata(ctx1)
+			d.Tracer = &r.Tracer
+			return handler(ctx1, d, &record)
+		})
+		if handlerErr != nil && err == nil {
+			err = handlerErr
+		}
+	}
+
+	return err
+}
+
+// handlerFor returns the handler for a record. Message attribute routes are checked first, then subject routes
+// and finally topic ARN routes, each in the order they were registered. The fall through handler is returned
+// (with false) when nothing matched.
+func (r *SNSRouter) handlerFor(record *SNSEventRecord) (SNSHandler, string, bool) {
+	for _, route := range r.attributeRoutes {
+		if value, ok := record.MessageAttribute(route.attribute); ok && route.matcher.Match(value) {
+			return route.handler, route.attribute + "=" + route.match, true
+		}
+	}
+	for _, route := range r.subjectRoutes {
+		if route.matcher.Match(record.SNS.Subject) {
+			return route.handler, route.match, true
+		}
+	}
+	for _, route := range r.topicRoutes {
+		if route.matcher.Match(record.SNS.TopicArn) {
+			return route.handler, route.match, true
+		}
+	}
+
+	// It's possible that the SNSRouter wasn't created with NewSNSRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *SNSEventRecord) error { return nil }, "*", false
+	}
+	return r.rootHandler, "*", false
+}
+
+// MessageAttribute returns the value of a message attribute given its name and whether or not it was set
+func (record *SNSEventRecord) MessageAttribute(name string) (string, bool) {
+	// Each attribute is an object, ie. {"Type": "String", "Value": "foo"}
+	if attr, ok := record.SNS.MessageAttributes[name].(map[string]interface{}); ok {
+		if value, ok := attr["Value"].(string); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// UnmarshalMessage will unmarshal the JSON message into the given value (typically a pointer to a struct)
+func (record *SNSEventRecord) UnmarshalMessage(v interface{}) error {
+	return json.Unmarshal([]byte(record.SNS.Message), v)
+}
+
+// S3Event will unwrap an S3 event notification that was published to an SNS topic
+func (record *SNSEventRecord) S3Event() (*S3Event, error) {
+	var evt S3Event
+	if err := record.UnmarshalMessage(&evt); err != nil {
+		return nil, err
+	}
+	// S3 also publishes an s3:TestEvent (with no records) when the notification is first configured.
+	if len(evt.Records) == 0 {
+		return nil, ErrNotS3Notification
+	}
+	return &evt, nil
+}
+
+// Listen will start an SNS listener that handles incoming notifications
+func (r *SNSRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewSNSRouter simply returns a new SNSRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewSNSRouter(rootHandler ...SNSHandler) *SNSRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and it returns nothing.
+	handler := func(context.Context, *HandlerDependencies, *SNSEventRecord) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &SNSRouter{
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for notifications from topics whose ARN matches the given glob
+func (r *SNSRouter) Handle(topicArnMatch string, handler SNSHandler) {
+	r.topicRoutes = append(r.topicRoutes, snsRoute{
+		match:   topicArnMatch,
+		matcher: glob.MustCompile(topicArnMatch),
+		handler: handler,
+	})
+}
+
+// HandleSubject will register a handler for notifications whose subject matches the given glob
+func (r *SNSRouter) HandleSubject(subjectMatch string, handler SNSHandler) {
+	r.subjectRoutes = append(r.subjectRoutes, snsRoute{
+		match:   subjectMatch,
+		matcher: glob.MustCompile(subjectMatch),
+		handler: handler,
+	})
+}
+
+// HandleAttribute will register a handler for notifications with a message attribute whose value matches the given glob
+func (r *SNSRouter) HandleAttribute(name string, valueMatch string, handler SNSHandler) {
+	r.attributeRoutes = append(r.attributeRoutes, snsRoute{
+		match:     valueMatch,
+		matcher:   glob.MustCompile(valueMatch),
+		attribute: name,
+		handler:   handler,
+	})
+}
+
+// HandleS3 will pass S3 event notifications published to topics matching the given glob along to an S3ObjectRouter.
+// This allows the same S3ObjectRouter to be used whether S3 invokes the Lambda directly or fans out through SNS.
+func (r *SNSRouter) HandleS3(topicArnMatch string, s3Router *S3ObjectRouter) {
+	r.Handle(topicArnMatch, func(ctx context.Context, d *HandlerDependencies, record *SNSEventRecord) error {
+		evt, err := record.S3Event()
+		if err == ErrNotS3Notification {
+			log.Println("ignoring SNS message without S3 records")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s3Router.LambdaHandler(ctx, d, *evt)
+	})
+}
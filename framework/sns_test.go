@@ -0,0 +1,127 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSNSRouter(t *testing.T) {
+	testHandler := func(ctx context.Context, d *HandlerDependencies, record *SNSEventRecord) error {
+		return nil
+	}
+
+	testRouter := NewSNSRouter()
+	Convey("NewSNSRouter", t, func() {
+		Convey("Should create a new SNSRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Handle("arn:aws:sns:*:orders", testHandler)
+	testRouter.HandleSubject("Refund *", testHandler)
+	testRouter.HandleAttribute("eventType", "order.*", testHandler)
+
+	Convey("handlerFor", t, func() {
+		record := SNSEventRecord{}
+		record.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:orders"
+
+		Convey("Should match a topic ARN glob", func() {
+			_, route, ok := testRouter.handlerFor(&record)
+			So(ok, ShouldBeTrue)
+			So(route, ShouldEqual, "arn:aws:sns:*:orders")
+		})
+
+		Convey("Should prefer a matching subject over the topic", func() {
+			record.SNS.Subject = "Refund issued"
+			_, route, _ := testRouter.handlerFor(&record)
+			So(route, ShouldEqual, "Refund *")
+		})
+
+		Convey("Should prefer a matching message attribute over the subject", func() {
+			record.SNS.Subject = "Refund issued"
+			record.SNS.MessageAttributes = map[string]interface{}{
+				"eventType": map[string]interface{}{"Type": "String", "Value": "order.refunded"},
+			}
+			_, route, _ := testRouter.handlerFor(&record)
+			So(route, ShouldEqual, "eventType=order.*")
+		})
+
+		Convey("Should fall through when nothing matches", func() {
+			record.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:invoices"
+			_, route, ok := testRouter.handlerFor(&record)
+			So(ok, ShouldBeFalse)
+			So(route, ShouldEqual, "*")
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		Convey("Should return the first error after handling every record", func() {
+			handled := []string{}
+			failingRouter := NewSNSRouter(func(ctx context.Context, d *HandlerDependencies, record *SNSEventRecord) error {
+				handled = append(handled, record.SNS.MessageID)
+				if record.SNS.Subject == "fail" {
+					return errors.New("could not process " + record.SNS.MessageID)
+				}
+				return nil
+			})
+			evt := SNSEvent{Records: make([]events.SNSEventRecord, 3)}
+			evt.Records[0].SNS.MessageID = "1"
+			evt.Records[0].SNS.Subject = "fail"
+			evt.Records[1].SNS.MessageID = "2"
+			evt.Records[1].SNS.Subject = "fail"
+			evt.Records[2].SNS.MessageID = "3"
+
+			err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, evt)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "could not process 1")
+			So(handled, ShouldResemble, []string{"1", "2", "3"})
+		})
+	})
+
+	Convey("S3Event", t, func() {
+		Convey("Should unwrap an S3 notification", func() {
+			record := SNSEventRecord{}
+			record.SNS.Message = `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"aegis-incoming"},"object":{"key":"image.png"}}}]}`
+			evt, err := record.S3Event()
+			So(err, ShouldBeNil)
+			So(evt.Records, ShouldHaveLength, 1)
+			So(evt.Records[0].S3.Object.Key, ShouldEqual, "image.png")
+		})
+
+		Convey("Should return an error for S3 test events", func() {
+			record := SNSEventRecord{}
+			record.SNS.Message = `{"Service":"Amazon S3","Event":"s3:TestEvent"}`
+			_, err := record.S3Event()
+			So(err, ShouldEqual, ErrNotS3Notification)
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify an SNSEvent", func() {
+			evt := map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"EventSource": "aws:sns", "Sns": map[string]interface{}{}},
+				},
+			}
+			So(getType(evt), ShouldEqual, "SNSEvent")
+		})
+	})
+}
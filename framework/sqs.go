@@ -0,0 +1,199 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/gobwas/glob"
+)
+
+// These types are not in the vendored aws-lambda-go package (SQS triggers came after it), nor is the
+// partial batch response which lets Lambda delete the successfully processed messages from the queue.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html
+
+// SQSEvent is a batch of messages received from an SQS queue
+type SQSEvent struct {
+	Records []SQSMessage `json:"Records"`
+}
+
+// SQSMessage is a single message (record) from an SQSEvent
+type SQSMessage struct {
+	MessageID              string                         `json:"messageId"`
+	ReceiptHandle          string                         `json:"receiptHandle"`
+	Body                   string                         `json:"body"`
+	Md5OfBody              string                         `json:"md5OfBody"`
+	Md5OfMessageAttributes string                         `json:"md5OfMessageAttributes"`
+	Attributes             map[string]string              `json:"attributes"`
+	MessageAttributes      map[string]SQSMessageAttribute `json:"messageAttributes"`
+	EventSourceARN         string                         `json:"eventSourceARN"`
+	EventSource            string                         `json:"eventSource"`
+	AWSRegion              string                         `json:"awsRegion"`
+	// DecodedBody is set by the SQSRouter. It holds the JSON decoded body or the raw body string if it was not JSON.
+	DecodedBody interface{} `json:"-"`
+}
+
+// SQSMessageAttribute is a custom attribute sent along with an SQS message
+type SQSMessageAttribute struct {
+	StringValue      *string  `json:"stringValue,omitempty"`
+	BinaryValue      []byte   `json:"binaryValue,omitempty"`
+	StringListValues []string `json:"stringListValues"`
+	BinaryListValues [][]byte `json:"binaryListValues"`
+	DataType         string   `json:"dataType"`
+}
+
+// SQSEventResponse is returned to Lambda so that only failed messages are retried.
+// The event source mapping must have the ReportBatchItemFailures function response type enabled.
+type SQSEventResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// SQSBatchItemFailure identifies a message that could not be processed
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// SQSRouter struct provides an interface to handle SQS messages (routers can route by queue ARN or message attribute)
+type SQSRouter struct {
+	routes          []sqsRoute
+	attributeRoutes []sqsRoute
+	rootHandler     SQSHandler
+	Tracer          TraceStrategy
+}
+
+// SQSHandler handles a single routed SQS message. Returning an error marks just that message as failed.
+type SQSHandler func(context.Context, *HandlerDependencies, *SQSMessage) error
+
+// sqsRoute holds a handler along with what it matches. Globs are compiled once upon registration.
+type sqsRoute struct {
+	match     string
+	matcher   glob.Glob
+	attribute string
+	handler   SQSHandler
+}
+
+// LambdaHandler handles SQS events. Each message is routed and traced on its own and any message whose
+// handler returned an error is reported back to Lambda as a batch item failure.
+func (r *SQSRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, evt SQSEvent) (SQSEventResponse, error) {
+	response := SQSEventResponse{BatchItemFailures: []SQSBatchItemFailure{}}
+
+	for i := range evt.Records {
+		record := &evt.Records[i]
+		record.DecodedBody = decodeSQSBody(record.Body)
+
+		handler, route, ok := r.handlerFor(record)
+		r.Tracer.Annotations = map[string]interface{}{
+			"SQSQueueArn":  record.EventSourceARN,
+			"SQSMessageID": record.MessageID,
+			"SQSRoute":     route,
+		}
+		if !ok {
+			log.Println("using default fall through handler")
+			r.Tracer.Annotations["FallthroughHandler"] = true
+		}
+
+		err := r.Tracer.Capture(ctx, "SQSHandler", func(ctx1 context.Context) error {
+			r.Tracer.AddAnnotations(ctx1)
+			r.Tracer.AddMetadata(ctx1)
+			d.Tracer = &r.Tracer
+			return handler(ctx1, d, record)
+		})
+		if err != nil {
+			log.Println("could not process SQS message", record.MessageID, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, SQSBatchItemFailure{ItemIdentifier: record.MessageID})
+		}
+	}
+
+	return response, nil
+}
+
+// handlerFor returns the handler for a message. Message attribute routes are checked first, then queue ARN routes,
+// each in the order they were registered. The fall through handler is returned (with false) when nothing matched.
+func (r *SQSRouter) handlerFor(record *SQSMessage) (SQSHandler, string, bool) {
+	for _, route := range r.attributeRoutes {
+		if attr, ok := record.MessageAttributes[route.attribute]; ok && attr.StringValue != nil {
+			if route.matcher.Match(*attr.StringValue) {
+				return route.handler, route.attribute + "=" + route.match, true
+			}
+		}
+	}
+	for _, route := range r.routes {
+		if route.matcher.Match(record.EventSourceARN) {
+			return route.handler, route.match, true
+		}
+	}
+
+	// It's possible that the SQSRouter wasn't created with NewSQSRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *SQSMessage) error { return nil }, "*", false
+	}
+	return r.rootHandler, "*", false
+}
+
+// decodeSQSBody will JSON decode a message body, falling back to the raw string when it isn't JSON
+func decodeSQSBody(body string) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		return body
+	}
+	return decoded
+}
+
+// UnmarshalBody will unmarshal the JSON message body into the given value (typically a pointer to a struct)
+func (m *SQSMessage) UnmarshalBody(v interface{}) error {
+	return json.Unmarshal([]byte(m.Body), v)
+}
+
+// Listen will start an SQS listener that handles incoming messages
+func (r *SQSRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewSQSRouter simply returns a new SQSRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewSQSRouter(rootHandler ...SQSHandler) *SQSRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and the message is considered handled.
+	handler := func(context.Context, *HandlerDependencies, *SQSMessage) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &SQSRouter{
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for messages from queues whose ARN matches the given glob
+// ie. "arn:aws:sqs:us-east-1:*:orders" or "*:orders-*"
+func (r *SQSRouter) Handle(queueArnMatch string, handler SQSHandler) {
+	r.routes = append(r.routes, sqsRoute{
+		match:   queueArnMatch,
+		matcher: glob.MustCompile(queueArnMatch),
+		handler: handler,
+	})
+}
+
+// HandleAttribute will register a handler for messages with a string message attribute whose value matches the given glob
+func (r *SQSRouter) HandleAttribute(name string, valueMatch string, handler SQSHandler) {
+	r.attributeRoutes = append(r.attributeRoutes, sqsRoute{
+		match:     valueMatch,
+		matcher:   glob.MustCompile(valueMatch),
+		attribute: name,
+		handler:   handler,
+	})
+}
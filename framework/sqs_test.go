@@ -0,0 +1,130 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSQSRouter(t *testing.T) {
+	queueHandled := ""
+	queueHandler := func(ctx context.Context, d *HandlerDependencies, msg *SQSMessage) error {
+		queueHandled = "queue"
+		return nil
+	}
+	attributeHandler := func(ctx context.Context, d *HandlerDependencies, msg *SQSMessage) error {
+		queueHandled = "attribute"
+		return nil
+	}
+
+	testRouter := NewSQSRouter()
+	Convey("NewSQSRouter", t, func() {
+		Convey("Should create a new SQSRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Handle("arn:aws:sqs:*:orders", queueHandler)
+	testRouter.HandleAttribute("type", "refund*", attributeHandler)
+
+	Convey("handlerFor", t, func() {
+		Convey("Should match a queue ARN glob", func() {
+			handler, route, ok := testRouter.handlerFor(&SQSMessage{EventSourceARN: "arn:aws:sqs:us-east-1:123456789012:orders"})
+			So(ok, ShouldBeTrue)
+			So(route, ShouldEqual, "arn:aws:sqs:*:orders")
+			handler(context.Background(), &HandlerDependencies{}, &SQSMessage{})
+			So(queueHandled, ShouldEqual, "queue")
+		})
+
+		Convey("Should prefer a matching message attribute", func() {
+			refund := "refund.created"
+			handler, route, ok := testRouter.handlerFor(&SQSMessage{
+				EventSourceARN:    "arn:aws:sqs:us-east-1:123456789012:orders",
+				MessageAttributes: map[string]SQSMessageAttribute{"type": {StringValue: &refund, DataType: "String"}},
+			})
+			So(ok, ShouldBeTrue)
+			So(route, ShouldEqual, "type=refund*")
+			handler(context.Background(), &HandlerDependencies{}, &SQSMessage{})
+			So(queueHandled, ShouldEqual, "attribute")
+		})
+
+		Convey("Should fall through when nothing matches", func() {
+			_, route, ok := testRouter.handlerFor(&SQSMessage{EventSourceARN: "arn:aws:sqs:us-east-1:123456789012:invoices"})
+			So(ok, ShouldBeFalse)
+			So(route, ShouldEqual, "*")
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		failingRouter := NewSQSRouter()
+		failingRouter.HandleAttribute("fail", "true", func(ctx context.Context, d *HandlerDependencies, msg *SQSMessage) error {
+			return errors.New("could not process")
+		})
+		fail := "true"
+		message := func(id string, failing bool) SQSMessage {
+			msg := SQSMessage{MessageID: id, EventSourceARN: "arn:aws:sqs:us-east-1:123456789012:orders", Body: "{}"}
+			if failing {
+				msg.MessageAttributes = map[string]SQSMessageAttribute{"fail": {StringValue: &fail, DataType: "String"}}
+			}
+			return msg
+		}
+
+		Convey("Should report only the failed messages as batch item failures", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, SQSEvent{
+				Records: []SQSMessage{message("1", false), message("2", true), message("3", false), message("4", true)},
+			})
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldResemble, []SQSBatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "4"}})
+		})
+
+		Convey("Should return an empty list when every message was processed", func() {
+			res, err := failingRouter.LambdaHandler(context.Background(), &HandlerDependencies{}, SQSEvent{
+				Records: []SQSMessage{message("1", false), message("2", false)},
+			})
+			So(err, ShouldBeNil)
+			So(res.BatchItemFailures, ShouldNotBeNil)
+			So(res.BatchItemFailures, ShouldBeEmpty)
+
+			b, _ := json.Marshal(res)
+			So(string(b), ShouldEqual, `{"batchItemFailures":[]}`)
+		})
+	})
+
+	Convey("decodeSQSBody", t, func() {
+		Convey("Should decode a JSON body", func() {
+			So(decodeSQSBody(`{"foo":"bar"}`), ShouldResemble, map[string]interface{}{"foo": "bar"})
+		})
+
+		Convey("Should return a raw body as a string", func() {
+			So(decodeSQSBody("not json"), ShouldEqual, "not json")
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify an SQSEvent", func() {
+			evt := map[string]interface{}{
+				"Records": []interface{}{
+					map[string]interface{}{"eventSource": "aws:sqs", "body": "hello"},
+				},
+			}
+			So(getType(evt), ShouldEqual, "SQSEvent")
+		})
+	})
+}
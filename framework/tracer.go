@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-xray-sdk-go/header"
 	"github.com/aws/aws-xray-sdk-go/xray"
 )
 
@@ -26,8 +27,15 @@ type TraceStrategy struct {
 	Annotations     map[string]interface{}
 	Metadata        map[string]interface{}
 	AWSClientTracer func(c *client.Client)
+	// Propagator adds the current trace's context to a carrier sent along with remote procedure calls (XRay by default)
+	Propagator func(ctx context.Context, carrier map[string]string)
+	// Restorer restores a caller's trace context from a carrier, returning the context to handle the call with (XRay by default)
+	Restorer func(ctx context.Context, carrier map[string]string) context.Context
 }
 
+// XRayTraceHeader is the carrier key for the XRay trace header, ie. "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+const XRayTraceHeader = "X-Amzn-Trace-Id"
+
 // Capture traces the provided synchronous function by using XRay Cpature() which puts a beginning and closing subsegment around its execution
 func (t *TraceStrategy) Capture(ctx context.Context, name string, fn func(context.Context) error) (err error) {
 	return xray.Capture(ctx, name, fn)
@@ -55,3 +63,37 @@ func (t *TraceStrategy) AddMetadata(ctx context.Context) {
 		}
 	}
 }
+
+// Propagate adds the current trace's context to the carrier, so the function called can continue the trace
+func (t *TraceStrategy) Propagate(ctx context.Context, carrier map[string]string) {
+	if t.Propagator != nil {
+		t.Propagator(ctx, carrier)
+		return
+	}
+	if seg := xray.GetSegment(ctx); seg != nil {
+		carrier[XRayTraceHeader] = seg.DownstreamHeader().String()
+		return
+	}
+	// Outside of a segment, Lambda's trace header for the invocation is used
+	if traceHeader, ok := ctx.Value(xray.LambdaTraceHeaderKey).(string); ok && traceHeader != "" {
+		carrier[XRayTraceHeader] = traceHeader
+	}
+}
+
+// Restore restores the caller's trace context from the carrier. Lambda begins the function's segment before the
+// handler is called, so XRay segments can't be made children of the caller's. Instead, the caller's trace ID is
+// added as a "CallerTraceID" annotation so the segments can be found from the caller's trace.
+func (t *TraceStrategy) Restore(ctx context.Context, carrier map[string]string) context.Context {
+	if t.Restorer != nil {
+		return t.Restorer(ctx, carrier)
+	}
+	if traceHeader := carrier[XRayTraceHeader]; traceHeader != "" {
+		if traceID := header.FromString(traceHeader).TraceID; traceID != "" {
+			if t.Annotations == nil {
+				t.Annotations = map[string]interface{}{}
+			}
+			t.Annotations["CallerTraceID"] = traceID
+		}
+	}
+	return ctx
+}
@@ -17,8 +17,13 @@
 package framework
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
+	"regexp"
+	"regexp/syntax"
 	"strings"
+	"unicode"
 )
 
 // route is a handler for an HTTP verb, plus it's middleware (if any).
@@ -28,63 +33,326 @@ type route struct {
 }
 
 // node represents a struct of each node in the tree.
+// Children are kept in the order they're matched: static segments, named params with a constraint,
+// named params and then a wildcard.
 type node struct {
 	children     []*node
 	component    string
 	isNamedParam bool
-	methods      map[string]*route
+	isWildcard   bool
+	// name is the param name for named params and wildcards
+	name string
+	// constraint is the regular expression a named param must match, ie. [0-9]+ for :id{[0-9]+}
+	constraint *regexp.Regexp
+	methods    map[string]*route
+}
+
+// paramConstraints are the named types that can be used as param constraints, ie. :id{int}
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"hex":   `[0-9a-fA-F]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
 }
 
 // addNode - adds a node to our tree. Will add multiple nodes if path
 // can be broken up into multiple components. Those nodes will have no
 // handler implemented and will fall through to the default handler.
+// addNode will panic if the route is invalid or ambiguous with one already added.
 func (n *node) addNode(method, path string, handler RouteHandler, middleware ...Middleware) {
 	components := strings.Split(path, "/")[1:]
-	count := len(components)
+	current := n
+	for i, component := range components {
+		child, err := current.addChild(component, i == len(components)-1)
+		if err != nil {
+			panic("invalid route " + method + " " + path + ": " + err.Error())
+		}
+		current = child
+	}
+
+	if current.methods == nil {
+		current.methods = make(map[string]*route)
+	}
+	if _, ok := current.methods[method]; ok {
+		panic("invalid route " + method + " " + path + ": it was already added")
+	}
+	r := route{handler: handler}
+	r.middleware = append(r.middleware, middleware...)
+	current.methods[method] = &r
+}
+
+// addChild returns the child node for a path component, adding it if needed
+func (n *node) addChild(component string, last bool) (*node, error) {
+	added, err := newNode(component)
+	if err != nil {
+		return nil, err
+	}
+	if added.isWildcard && !last {
+		return nil, errors.New("wildcard " + component + " must be the last segment")
+	}
+
+	for _, child := range n.children {
+		if child.component == component {
+			return child, nil
+		}
+		if child.conflicts(added) {
+			return nil, fmt.Errorf("%s is ambiguous with %s", component, child.component)
+		}
+	}
+
+	// Insert after the last child that is matched first
+	i := len(n.children)
+	for i > 0 && n.children[i-1].rank() > added.rank() {
+		i--
+	}
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = added
+	return added, nil
+}
 
-	for {
-		aNode, component := n.traverse(components, nil)
-		if aNode.component == component && count == 1 { // update an existing node.
-			r := route{handler: handler}
-			r.middleware = append(r.middleware, middleware...)
-			aNode.methods[method] = &r
-			return
+// newNode returns a node for a path component: a static segment, named param (:name or :name{constraint})
+// or wildcard (*name)
+func newNode(component string) (*node, error) {
+	n := &node{component: component, methods: make(map[string]*route)}
+	switch {
+	case strings.HasPrefix(component, ":"):
+		n.isNamedParam = true
+		n.name = component[1:]
+		if i := strings.Index(n.name, "{"); i > -1 {
+			if !strings.HasSuffix(n.name, "}") {
+				return nil, errors.New("param constraint for " + component + " must end with }")
+			}
+			pattern := n.name[i+1 : len(n.name)-1]
+			n.name = n.name[:i]
+			if p, ok := paramConstraints[pattern]; ok {
+				pattern = p
+			}
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, err
+			}
+			n.constraint = re
+		}
+		if n.name == "" {
+			return nil, errors.New("param " + component + " must be named, ie. :id")
+		}
+	case strings.HasPrefix(component, "*"):
+		n.isWildcard = true
+		n.name = component[1:]
+		if n.name == "" {
+			return nil, errors.New("wildcard must be named, ie. *path")
+		}
+	}
+	return n, nil
+}
+
+// rank returns the order children are matched in
+func (n *node) rank() int {
+	switch {
+	case n.isWildcard:
+		return 3
+	case n.isNamedParam && n.constraint == nil:
+		return 2
+	case n.isNamedParam:
+		return 1
+	}
+	return 0
+}
+
+// conflicts returns whether or not a request could match both nodes, with neither taking precedence.
+// This is the case for wildcards, named params without constraints and named params with constraints that aren't
+// provably disjoint (see disjointConstraints), ie. :id{int} and :number{[0-9]{3}} both match "123".
+func (n *node) conflicts(other *node) bool {
+	switch {
+	case n.isWildcard && other.isWildcard:
+		return true
+	case n.isNamedParam && other.isNamedParam:
+		if n.constraint == nil || other.constraint == nil {
+			return n.constraint == nil && other.constraint == nil
+		}
+		return !disjointConstraints(n.constraint, other.constraint)
+	}
+	return false
+}
+
+// disjointConstraints returns whether or not no path component can match both constraints. That's proven when
+// the characters they match don't overlap (ie. int and alpha) or the lengths they match don't (ie. uuid and
+// [0-9]{3}). Constraints that might overlap are treated as though they do.
+func disjointConstraints(a, b *regexp.Regexp) bool {
+	reA, errA := syntax.Parse(a.String(), syntax.Perl)
+	reB, errB := syntax.Parse(b.String(), syntax.Perl)
+	if errA != nil || errB != nil {
+		return false
+	}
+	minA, maxA := matchLength(reA)
+	minB, maxB := matchLength(reB)
+	if (maxA > -1 && maxA < minB) || (maxB > -1 && maxB < minA) {
+		return true
+	}
+	runesA, runesB := matchRunes(reA), matchRunes(reB)
+	for i := 0; i < len(runesA); i += 2 {
+		for j := 0; j < len(runesB); j += 2 {
+			if runesA[i] <= runesB[j+1] && runesB[j] <= runesA[i+1] {
+				return false
+			}
 		}
-		newNode := node{component: component, isNamedParam: false, methods: make(map[string]*route)}
+	}
+	return true
+}
 
-		if len(component) > 0 && component[0] == ':' { // check if it is a named param.
-			newNode.isNamedParam = true
+// matchLength returns the minimum and maximum number of characters a regular expression matches (-1 is unbounded)
+func matchLength(re *syntax.Regexp) (int, int) {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return 0, 0
+	case syntax.OpLiteral:
+		return len(re.Rune), len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1, 1
+	case syntax.OpCapture:
+		return matchLength(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := matchLength(re.Sub[0])
+		low, high := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			low, high = 0, -1
+		case syntax.OpPlus:
+			low, high = 1, -1
+		case syntax.OpQuest:
+			low, high = 0, 1
 		}
-		if count == 1 { // this is the last component of the url resource, so it gets the handler.
-			r := route{handler: handler}
-			r.middleware = append(r.middleware, middleware...)
-			newNode.methods[method] = &r
+		return min * low, repeatedLength(max, high)
+	case syntax.OpConcat:
+		min, max := 0, 0
+		for _, sub := range re.Sub {
+			subMin, subMax := matchLength(sub)
+			min += subMin
+			if subMax == -1 {
+				max = -1
+			} else if max > -1 {
+				max += subMax
+			}
 		}
-		aNode.children = append(aNode.children, &newNode)
-		count--
-		if count == 0 {
-			break
+		return min, max
+	case syntax.OpAlternate:
+		min, max := matchLength(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			subMin, subMax := matchLength(sub)
+			if subMin < min {
+				min = subMin
+			}
+			if max > -1 && (subMax == -1 || subMax > max) {
+				max = subMax
+			}
+		}
+		return min, max
+	}
+	return 0, -1
+}
+
+// repeatedLength returns the maximum length of a match repeated up to the given times (-1 is unbounded)
+func repeatedLength(max int, times int) int {
+	switch {
+	case max == 0 || times == 0:
+		return 0
+	case max == -1 || times == -1:
+		return -1
+	}
+	return max * times
+}
+
+// matchRunes returns the ranges of characters (in pairs, like syntax.Regexp.Rune) a regular expression can match
+func matchRunes(re *syntax.Regexp) []rune {
+	switch re.Op {
+	case syntax.OpLiteral:
+		var runes []rune
+		for _, r := range re.Rune {
+			runes = append(runes, r, r)
+			if re.Flags&syntax.FoldCase != 0 {
+				for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+					runes = append(runes, f, f)
+				}
+			}
 		}
+		return runes
+	case syntax.OpCharClass:
+		return re.Rune
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return []rune{0, unicode.MaxRune}
 	}
+	var runes []rune
+	for _, sub := range re.Sub {
+		runes = append(runes, matchRunes(sub)...)
+	}
+	return runes
 }
 
 // traverse moves along the tree adding named params as it comes and across them.
-// Returns the node and component found.
+// Returns the node and component found. Static segments take precedence over named params (those with
+// constraints first) and named params take precedence over wildcards. If no route matches the path,
+// the node traverse was called on is returned.
 func (n *node) traverse(components []string, params url.Values) (*node, string) {
+	if len(components) == 0 {
+		return n, ""
+	}
+	component := components[len(components)-1]
+	found, values := n.match(components)
+	if found == nil {
+		return n, component
+	}
+	if params != nil {
+		for i := 0; i < len(values); i += 2 {
+			params.Add(values[i], values[i+1])
+		}
+	}
+	return found, component
+}
+
+// match returns the node with routes that matches the path components, along with the param names and values
+// (in pairs) for the matched path. Matching backtracks, so /users/:id/posts still matches /users/new/posts when
+// there's also a /users/new route.
+func (n *node) match(components []string) (*node, []string) {
+	if len(components) == 0 {
+		if len(n.methods) > 0 {
+			return n, nil
+		}
+		return nil, nil
+	}
+
 	component := components[0]
-	if len(n.children) > 0 { // no children, then bail out.
-		for _, child := range n.children {
-			if component == child.component || child.isNamedParam {
-				if child.isNamedParam && params != nil {
-					params.Add(child.component[1:], component)
-				}
-				next := components[1:]
-				if len(next) > 0 { // http://xkcd.com/1270/
-					return child.traverse(next, params) // tail recursion is it's own reward.
-				}
-				return child, component
+	for _, child := range n.children {
+		switch {
+		case child.isWildcard:
+			if len(child.methods) > 0 {
+				return child, []string{child.name, strings.Join(components, "/")}
+			}
+		case child.isNamedParam:
+			if component == "" || (child.constraint != nil && !child.constraint.MatchString(component)) {
+				continue
+			}
+			if found, values := child.match(components[1:]); found != nil {
+				return found, append([]string{child.name, component}, values...)
+			}
+		case child.component == component:
+			if found, values := child.match(components[1:]); found != nil {
+				return found, values
 			}
 		}
 	}
-	return n, component
+	return nil, nil
+}
+
+// walk calls fn for each route in the tree below the node, with the route's path and method
+func (n *node) walk(path string, fn func(method, path string, r *route)) {
+	for _, child := range n.children {
+		childPath := path + "/" + child.component
+		for method, r := range child.methods {
+			fn(method, childPath, r)
+		}
+		child.walk(childPath, fn)
+	}
 }
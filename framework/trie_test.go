@@ -59,3 +59,118 @@ func TestTrie(t *testing.T) {
 		})
 	})
 }
+
+func TestTrieMatching(t *testing.T) {
+	handler := func(name string) RouteHandler {
+		return func(ctx context.Context, d *HandlerDependencies, req *APIGatewayProxyRequest, res *APIGatewayProxyResponse, params url.Values) error {
+			res.Body = name
+			return nil
+		}
+	}
+	tree := &node{component: "/", methods: make(map[string]*route)}
+	tree.addNode("GET", "/users/:id/posts", handler("posts"))
+	tree.addNode("GET", "/users/new", handler("new"))
+	tree.addNode("GET", "/users/:id", handler("user"))
+	tree.addNode("GET", "/users/:id{int}/avatar", handler("avatar"))
+	tree.addNode("GET", "/orders/:id{uuid}", handler("order"))
+	tree.addNode("GET", "/orders/:number{[0-9]{3}}", handler("order number"))
+	tree.addNode("GET", "/files/*path", handler("files"))
+	tree.addNode("GET", "/files/readme", handler("readme"))
+	tree.addNode("GET", "/status", handler("status"))
+
+	match := func(path string) (string, url.Values) {
+		params := url.Values{}
+		n, _ := tree.traverse(strings.Split(path, "/")[1:], params)
+		r, ok := n.methods["GET"]
+		if !ok {
+			return "", params
+		}
+		res := APIGatewayProxyResponse{}
+		r.handler(context.Background(), &HandlerDependencies{}, &APIGatewayProxyRequest{}, &res, params)
+		return res.Body, params
+	}
+
+	Convey("traverse", t, func() {
+		Convey("Should give static segments precedence over named params, regardless of the order they were added", func() {
+			name, params := match("/users/new")
+			So(name, ShouldEqual, "new")
+			So(params, ShouldBeEmpty)
+			name, params = match("/users/42")
+			So(name, ShouldEqual, "user")
+			So(params.Get("id"), ShouldEqual, "42")
+		})
+
+		Convey("Should backtrack to named params when a static segment doesn't lead to a route", func() {
+			name, params := match("/users/new/posts")
+			So(name, ShouldEqual, "posts")
+			So(params.Get("id"), ShouldEqual, "new")
+		})
+
+		Convey("Should match param constraints", func() {
+			name, _ := match("/users/42/avatar")
+			So(name, ShouldEqual, "avatar")
+			name, _ = match("/users/abc/avatar")
+			So(name, ShouldBeEmpty)
+			name, params := match("/orders/123")
+			So(name, ShouldEqual, "order number")
+			So(params.Get("number"), ShouldEqual, "123")
+			name, _ = match("/orders/f47ac10b-58cc-4372-a567-0e02b2c3d479")
+			So(name, ShouldEqual, "order")
+			name, _ = match("/orders/1234")
+			So(name, ShouldBeEmpty)
+		})
+
+		Convey("Should match the rest of the path with a wildcard", func() {
+			name, params := match("/files/docs/guide.md")
+			So(name, ShouldEqual, "files")
+			So(params.Get("path"), ShouldEqual, "docs/guide.md")
+			name, _ = match("/files/readme")
+			So(name, ShouldEqual, "readme")
+			name, _ = match("/files")
+			So(name, ShouldBeEmpty)
+		})
+
+		Convey("Should not match a route for part of the path", func() {
+			name, _ := match("/status/extra")
+			So(name, ShouldBeEmpty)
+		})
+	})
+
+	Convey("addNode", t, func() {
+		Convey("Should panic for ambiguous routes", func() {
+			So(func() { tree.addNode("GET", "/users/:name", handler("name")) }, ShouldPanicWith, "invalid route GET /users/:name: :name is ambiguous with :id")
+			So(func() { tree.addNode("GET", "/orders/:uuid{uuid}", handler("uuid")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/files/*rest", handler("rest")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/orders/:id{int}", handler("id")) }, ShouldPanic)
+			tree.addNode("GET", "/codes/:n{[0-9]{3}}", handler("code"))
+			So(func() { tree.addNode("GET", "/codes/:id{int}", handler("id")) }, ShouldPanicWith, "invalid route GET /codes/:id{int}: :id{int} is ambiguous with :n{[0-9]{3}}")
+			So(func() { tree.addNode("GET", "/status", handler("status")) }, ShouldPanicWith, "invalid route GET /status: it was already added")
+		})
+
+		Convey("Should panic for invalid routes", func() {
+			So(func() { tree.addNode("GET", "/files/*path/more", handler("more")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/things/:id{[0-9}", handler("bad")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/things/:", handler("unnamed")) }, ShouldPanic)
+		})
+
+		Convey("Should allow sibling param constraints that are disjoint", func() {
+			So(func() { tree.addNode("GET", "/things/:id{int}", handler("thing")) }, ShouldNotPanic)
+			So(func() { tree.addNode("GET", "/things/:slug{alpha}", handler("slug")) }, ShouldNotPanic)
+			So(func() { tree.addNode("GET", "/things/:code{[A-Z]{2}-[0-9]{4}}", handler("code")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/things/:hex{hex}", handler("hex")) }, ShouldPanic)
+			So(func() { tree.addNode("GET", "/things/:sep{[_.]+}", handler("sep")) }, ShouldNotPanic)
+			So(func() { tree.addNode("GET", "/things/:upper{(?i)x+}", handler("upper")) }, ShouldPanic)
+
+			name, params := match("/things/7")
+			So(name, ShouldEqual, "thing")
+			So(params.Get("id"), ShouldEqual, "7")
+			name, params = match("/things/abc")
+			So(name, ShouldEqual, "slug")
+			So(params.Get("slug"), ShouldEqual, "abc")
+		})
+
+		Convey("Should allow other methods on the same route", func() {
+			So(func() { tree.addNode("POST", "/users/:id", handler("update")) }, ShouldNotPanic)
+		})
+	})
+}
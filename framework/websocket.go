@@ -0,0 +1,228 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// WebSocketRequest is the event API Gateway sends for WebSocket API routes.
+// This is not in the vendored aws-lambda-go package.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-websocket-api-integration-requests.html
+type WebSocketRequest struct {
+	Headers               map[string]string       `json:"headers"`
+	MultiValueHeaders     map[string][]string     `json:"multiValueHeaders"`
+	QueryStringParameters map[string]string       `json:"queryStringParameters"`
+	StageVariables        map[string]string       `json:"stageVariables"`
+	RequestContext        WebSocketRequestContext `json:"requestContext"`
+	Body                  string                  `json:"body"`
+	IsBase64Encoded       bool                    `json:"isBase64Encoded"`
+}
+
+// WebSocketRequestContext holds the connection and route information for a WebSocketRequest
+type WebSocketRequestContext struct {
+	RouteKey          string                 `json:"routeKey"`
+	EventType         string                 `json:"eventType"`
+	ConnectionID      string                 `json:"connectionId"`
+	ConnectedAt       int64                  `json:"connectedAt"`
+	MessageID         string                 `json:"messageId"`
+	MessageDirection  string                 `json:"messageDirection"`
+	ExtendedRequestID string                 `json:"extendedRequestId"`
+	RequestID         string                 `json:"requestId"`
+	RequestTimeEpoch  int64                  `json:"requestTimeEpoch"`
+	DomainName        string                 `json:"domainName"`
+	Stage             string                 `json:"stage"`
+	APIID             string                 `json:"apiId"`
+	Identity          WebSocketIdentity      `json:"identity"`
+	Authorizer        map[string]interface{} `json:"authorizer"`
+}
+
+// WebSocketIdentity holds information about the client of a WebSocket connection
+type WebSocketIdentity struct {
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// Predefined WebSocket API route keys
+const (
+	WebSocketConnect    = "$connect"
+	WebSocketDisconnect = "$disconnect"
+	WebSocketDefault    = "$default"
+)
+
+// WebSocketRouter struct provides an interface to handle API Gateway WebSocket API routes (by route key)
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-websocket-api.html
+type WebSocketRouter struct {
+	handlers    map[string]WebSocketHandler
+	rootHandler WebSocketHandler
+	Tracer      TraceStrategy
+}
+
+// WebSocketHandler handles a WebSocket route. The response is only sent back to the client for $connect
+// (where a non 2xx status rejects the connection) or routes configured with a route response.
+type WebSocketHandler func(context.Context, *HandlerDependencies, *WebSocketRequest, *APIGatewayProxyResponse) error
+
+// LambdaHandler handles WebSocket API events.
+func (r *WebSocketRouter) LambdaHandler(ctx context.Context, d *HandlerDependencies, req WebSocketRequest) (APIGatewayProxyResponse, error) {
+	res := APIGatewayProxyResponse{StatusCode: 200}
+
+	// Unless a client was configured (ie. for a custom domain name), use the API the request came through
+	// to manage connections. Messages can then be sent back to the client with d.Services.WebSocket
+	if d.Services == nil || d.Services.WebSocket == nil {
+		d = d.withWebSocketClient(req.Endpoint())
+	}
+
+	handler, ok := r.handlerFor(&req)
+	r.Tracer.Annotations = map[string]interface{}{
+		"WebSocketRouteKey":     req.RequestContext.RouteKey,
+		"WebSocketEventType":    req.RequestContext.EventType,
+		"WebSocketConnectionID": req.RequestContext.ConnectionID,
+	}
+	if !ok {
+		log.Println("using default fall through handler")
+		r.Tracer.Annotations["FallthroughHandler"] = true
+	}
+
+	err := r.Tracer.Capture(ctx, "WebSocketHandler", func(ctx1 context.Context) error {
+		r.Tracer.AddAnnotations(ctx1)
+		r.Tracer.AddMetadata(ctx1)
+		d.Tracer = &r.Tracer
+		return handler(ctx1, d, &req, &res)
+	})
+
+	// Just like Router, errors are returned as a response rather than failing the invocation.
+	// For $connect this means the connection is rejected.
+	if err != nil {
+		res.Error(500, err)
+	}
+	return res, nil
+}
+
+// withWebSocketClient returns a copy of the dependencies with a WebSocket client for the endpoint. The services are
+// copied too, they're shared by every invocation and requests can come through different APIs and stages.
+func (d *HandlerDependencies) withWebSocketClient(endpoint string) *HandlerDependencies {
+	var services Services
+	if d.Services != nil {
+		services = *d.Services
+	}
+	svc, err := NewWebSocketClient(&WebSocketClientConfig{Endpoint: endpoint})
+	if err != nil {
+		log.Println("WebSocket client could not be configured", err)
+	}
+	services.WebSocket = svc
+
+	scoped := *d
+	scoped.Services = &services
+	return &scoped
+}
+
+// handlerFor returns the handler registered for the request's route key, or the fall through handler (with false)
+// when nothing matched. API Gateway itself sends $default for messages that don't match any route selection.
+func (r *WebSocketRouter) handlerFor(req *WebSocketRequest) (WebSocketHandler, bool) {
+	if handler, ok := r.handlers[req.RequestContext.RouteKey]; ok {
+		return handler, true
+	}
+
+	// It's possible that the WebSocketRouter wasn't created with NewWebSocketRouter, so check for this still.
+	if r.rootHandler == nil {
+		return func(context.Context, *HandlerDependencies, *WebSocketRequest, *APIGatewayProxyResponse) error {
+			return nil
+		}, false
+	}
+	return r.rootHandler, false
+}
+
+// Endpoint returns the API Gateway Management API endpoint for the API and stage the request came through
+func (req *WebSocketRequest) Endpoint() string {
+	return "https://" + req.RequestContext.DomainName + "/" + req.RequestContext.Stage
+}
+
+// GetHeader will return the value for a given header (case-insensitive)
+func (req *WebSocketRequest) GetHeader(key string) string {
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// GetBody will return the message body, decoding it first if it was base64 encoded (binary frames)
+func (req *WebSocketRequest) GetBody() (string, error) {
+	if req.IsBase64Encoded {
+		b, err := b64.StdEncoding.DecodeString(req.Body)
+		return string(b), err
+	}
+	return req.Body, nil
+}
+
+// UnmarshalBody will unmarshal the JSON message body into the given value (typically a pointer to a struct)
+func (req *WebSocketRequest) UnmarshalBody(v interface{}) error {
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
+
+// Listen will start a WebSocket listener that handles incoming route events
+func (r *WebSocketRouter) Listen() {
+	lambda.Start(r.LambdaHandler)
+}
+
+// NewWebSocketRouter simply returns a new WebSocketRouter struct and behaves a bit like Router, it even takes an optional rootHandler or "fall through" catch all
+func NewWebSocketRouter(rootHandler ...WebSocketHandler) *WebSocketRouter {
+	// The catch all is optional, if not provided, an empty handler is still called and it returns a 200.
+	handler := func(context.Context, *HandlerDependencies, *WebSocketRequest, *APIGatewayProxyResponse) error {
+		return nil
+	}
+	if len(rootHandler) > 0 {
+		handler = rootHandler[0]
+	}
+	return &WebSocketRouter{
+		handlers:    make(map[string]WebSocketHandler),
+		rootHandler: handler,
+	}
+}
+
+// Handle will register a handler for the given route key (ie. the "action" in a message for the
+// default route selection expression of $request.body.action)
+func (r *WebSocketRouter) Handle(routeKey string, handler WebSocketHandler) {
+	if r.handlers == nil {
+		r.handlers = make(map[string]WebSocketHandler)
+	}
+	r.handlers[routeKey] = handler
+}
+
+// Connect is the same as Handle only the $connect route key is already implied.
+func (r *WebSocketRouter) Connect(handler WebSocketHandler) {
+	r.Handle(WebSocketConnect, handler)
+}
+
+// Disconnect is the same as Handle only the $disconnect route key is already implied.
+func (r *WebSocketRouter) Disconnect(handler WebSocketHandler) {
+	r.Handle(WebSocketDisconnect, handler)
+}
+
+// Default is the same as Handle only the $default route key is already implied.
+func (r *WebSocketRouter) Default(handler WebSocketHandler) {
+	r.Handle(WebSocketDefault, handler)
+}
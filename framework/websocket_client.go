@@ -0,0 +1,175 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// WebSocketClient is an interface for managing WebSocket API connections through the API Gateway Management API.
+// The vendored AWS SDK predates the apigatewaymanagementapi package, so requests are signed and sent directly.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-how-to-call-websocket-api-connections.html
+type WebSocketClient struct {
+	Endpoint   string
+	Region     string
+	HTTPClient *http.Client
+	signer     *v4.Signer
+}
+
+// WebSocketClientConfig defines required info to build a new WebSocketClient
+type WebSocketClientConfig struct {
+	// Endpoint is the API's callback URL, ie. https://{api-id}.execute-api.{region}.amazonaws.com/{stage}
+	Endpoint string `json:"endpoint"`
+	// Region is optional, it is taken from the endpoint when possible
+	Region     string       `json:"region"`
+	HTTPClient *http.Client `json:"-"`
+}
+
+// WebSocketConnection holds information about a connected WebSocket client
+type WebSocketConnection struct {
+	ConnectedAt  time.Time         `json:"connectedAt"`
+	LastActiveAt time.Time         `json:"lastActiveAt"`
+	Identity     WebSocketIdentity `json:"identity"`
+}
+
+var (
+	// ErrWebSocketConnectionGone is returned when the connection no longer exists (the client has disconnected)
+	ErrWebSocketConnectionGone = errors.New("WebSocket connection is gone")
+
+	executeAPIRegion = regexp.MustCompile(`execute-api\.([a-z0-9-]+)\.amazonaws\.com`)
+)
+
+// NewWebSocketClient returns a new WebSocketClient for the given WebSocket API endpoint
+func NewWebSocketClient(cfg *WebSocketClientConfig) (*WebSocketClient, error) {
+	c := &WebSocketClient{
+		Endpoint:   strings.TrimRight(cfg.Endpoint, "/"),
+		Region:     cfg.Region,
+		HTTPClient: cfg.HTTPClient,
+	}
+	if c.Endpoint == "" {
+		return c, errors.New("WebSocket API endpoint is required")
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return c, err
+	}
+	c.signer = v4.NewSigner(sess.Config.Credentials)
+
+	// Custom domain names won't contain the region, so fall back to the session (and then Lambda's environment).
+	if c.Region == "" {
+		if matches := executeAPIRegion.FindStringSubmatch(c.Endpoint); len(matches) == 2 {
+			c.Region = matches[1]
+		}
+	}
+	if c.Region == "" {
+		c.Region = aws.StringValue(sess.Config.Region)
+	}
+	if c.Region == "" {
+		c.Region = os.Getenv("AWS_REGION")
+	}
+
+	return c, nil
+}
+
+// PostToConnection will send data to a connected client
+func (c *WebSocketClient) PostToConnection(ctx context.Context, connectionID string, data []byte) error {
+	_, err := c.do(ctx, http.MethodPost, connectionID, data)
+	return err
+}
+
+// PostJSON will marshal the given value to JSON and send it to a connected client
+func (c *WebSocketClient) PostJSON(ctx context.Context, connectionID string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.PostToConnection(ctx, connectionID, data)
+}
+
+// GetConnection will return information about a connected client
+func (c *WebSocketClient) GetConnection(ctx context.Context, connectionID string) (*WebSocketConnection, error) {
+	body, err := c.do(ctx, http.MethodGet, connectionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	var conn WebSocketConnection
+	err = json.Unmarshal(body, &conn)
+	return &conn, err
+}
+
+// DeleteConnection will disconnect a client
+func (c *WebSocketClient) DeleteConnection(ctx context.Context, connectionID string) error {
+	_, err := c.do(ctx, http.MethodDelete, connectionID, nil)
+	return err
+}
+
+// connectionURL returns the Management API URL for a connection
+func (c *WebSocketClient) connectionURL(connectionID string) string {
+	return c.Endpoint + "/@connections/" + url.PathEscape(connectionID)
+}
+
+// do signs and sends a request to the Management API, returning the response body
+func (c *WebSocketClient) do(ctx context.Context, method string, connectionID string, data []byte) ([]byte, error) {
+	if c.signer == nil {
+		return nil, errors.New("WebSocket client is not configured, use NewWebSocketClient")
+	}
+
+	req, err := http.NewRequest(method, c.connectionURL(connectionID), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	if _, err = c.signer.Sign(req, bytes.NewReader(data), "execute-api", c.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return body, ErrWebSocketConnectionGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return body, errors.New("WebSocket connection request failed: " + resp.Status + " " + string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,214 @@
+// Copyright © 2016 Tom Maiaroto <tom@shift8creative.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWebSocketRouter(t *testing.T) {
+	routeHandled := ""
+	connectHandler := func(ctx context.Context, d *HandlerDependencies, req *WebSocketRequest, res *APIGatewayProxyResponse) error {
+		routeHandled = "connect"
+		return nil
+	}
+	sendHandler := func(ctx context.Context, d *HandlerDependencies, req *WebSocketRequest, res *APIGatewayProxyResponse) error {
+		routeHandled = "sendMessage"
+		return nil
+	}
+
+	testRouter := NewWebSocketRouter()
+	Convey("NewWebSocketRouter", t, func() {
+		Convey("Should create a new WebSocketRouter", func() {
+			So(testRouter, ShouldNotBeNil)
+		})
+	})
+
+	testRouter.Connect(connectHandler)
+	testRouter.Handle("sendMessage", sendHandler)
+
+	Convey("handlerFor", t, func() {
+		Convey("Should match the $connect route key", func() {
+			req := WebSocketRequest{}
+			req.RequestContext.RouteKey = WebSocketConnect
+			handler, ok := testRouter.handlerFor(&req)
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &req, &APIGatewayProxyResponse{})
+			So(routeHandled, ShouldEqual, "connect")
+		})
+
+		Convey("Should match a custom route key", func() {
+			req := WebSocketRequest{}
+			req.RequestContext.RouteKey = "sendMessage"
+			handler, ok := testRouter.handlerFor(&req)
+			So(ok, ShouldBeTrue)
+			handler(context.Background(), &HandlerDependencies{}, &req, &APIGatewayProxyResponse{})
+			So(routeHandled, ShouldEqual, "sendMessage")
+		})
+
+		Convey("Should fall through when the route key is not registered", func() {
+			req := WebSocketRequest{}
+			req.RequestContext.RouteKey = WebSocketDisconnect
+			_, ok := testRouter.handlerFor(&req)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("LambdaHandler", t, func() {
+		Convey("Should use a client for the API each request came through without changing the shared services", func() {
+			endpoints := []string{}
+			router := NewWebSocketRouter(func(ctx context.Context, d *HandlerDependencies, req *WebSocketRequest, res *APIGatewayProxyResponse) error {
+				endpoints = append(endpoints, d.Services.WebSocket.Endpoint)
+				return nil
+			})
+			services := &Services{}
+			d := &HandlerDependencies{Services: services}
+			request := func(domainName string, stage string) WebSocketRequest {
+				req := WebSocketRequest{}
+				req.RequestContext.RouteKey = WebSocketDefault
+				req.RequestContext.DomainName = domainName
+				req.RequestContext.Stage = stage
+				return req
+			}
+
+			router.LambdaHandler(context.Background(), d, request("abc123.execute-api.us-east-1.amazonaws.com", "prod"))
+			router.LambdaHandler(context.Background(), d, request("def456.execute-api.us-west-2.amazonaws.com", "dev"))
+			So(endpoints, ShouldResemble, []string{
+				"https://abc123.execute-api.us-east-1.amazonaws.com/prod",
+				"https://def456.execute-api.us-west-2.amazonaws.com/dev",
+			})
+			So(services.WebSocket, ShouldBeNil)
+			So(d.Services, ShouldEqual, services)
+		})
+
+		Convey("Should use a configured client", func() {
+			var endpoint string
+			router := NewWebSocketRouter(func(ctx context.Context, d *HandlerDependencies, req *WebSocketRequest, res *APIGatewayProxyResponse) error {
+				endpoint = d.Services.WebSocket.Endpoint
+				return nil
+			})
+			d := &HandlerDependencies{Services: &Services{WebSocket: &WebSocketClient{Endpoint: "https://ws.example.com"}}}
+			req := WebSocketRequest{}
+			req.RequestContext.DomainName = "abc123.execute-api.us-east-1.amazonaws.com"
+			router.LambdaHandler(context.Background(), d, req)
+			So(endpoint, ShouldEqual, "https://ws.example.com")
+		})
+	})
+
+	Convey("WebSocketRequest", t, func() {
+		req := WebSocketRequest{}
+		req.RequestContext.DomainName = "abc123.execute-api.us-east-1.amazonaws.com"
+		req.RequestContext.Stage = "prod"
+
+		Convey("Should return the Management API endpoint", func() {
+			So(req.Endpoint(), ShouldEqual, "https://abc123.execute-api.us-east-1.amazonaws.com/prod")
+		})
+
+		Convey("Should decode a base64 encoded body", func() {
+			req.Body = "eyJhY3Rpb24iOiJzZW5kTWVzc2FnZSJ9"
+			req.IsBase64Encoded = true
+			var msg map[string]string
+			So(req.UnmarshalBody(&msg), ShouldBeNil)
+			So(msg["action"], ShouldEqual, "sendMessage")
+		})
+	})
+
+	Convey("getType", t, func() {
+		Convey("Should identify a WebSocketRequest", func() {
+			evt := map[string]interface{}{
+				"requestContext": map[string]interface{}{"routeKey": "$connect", "eventType": "CONNECT", "connectionId": "L0SM9cOFvHcCIhw="},
+			}
+			So(getType(evt), ShouldEqual, "WebSocketRequest")
+		})
+	})
+}
+
+func TestWebSocketClient(t *testing.T) {
+	// Signing needs credentials, they don't need to be valid for the test server
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+
+	var method, path, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.EscapedPath()
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"connectedAt":"2018-11-29T18:22:00.000Z","identity":{"sourceIp":"127.0.0.1","userAgent":"wscat"},"lastActiveAt":"2018-11-29T18:23:00.000Z"}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewWebSocketClient(&WebSocketClientConfig{Endpoint: ts.URL + "/prod/", Region: "us-east-1"})
+
+	Convey("NewWebSocketClient", t, func() {
+		Convey("Should create a new WebSocketClient", func() {
+			So(err, ShouldBeNil)
+			So(client.Endpoint, ShouldEqual, ts.URL+"/prod")
+		})
+
+		Convey("Should take the region from the endpoint", func() {
+			c, _ := NewWebSocketClient(&WebSocketClientConfig{Endpoint: "https://abc123.execute-api.eu-west-1.amazonaws.com/prod"})
+			So(c.Region, ShouldEqual, "eu-west-1")
+		})
+
+		Convey("Should require an endpoint", func() {
+			_, err := NewWebSocketClient(&WebSocketClientConfig{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("PostJSON", t, func() {
+		Convey("Should post a signed message to the connection", func() {
+			err := client.PostJSON(context.Background(), "L0SM9cOFvHcCIhw=", map[string]string{"message": "hello"})
+			So(err, ShouldBeNil)
+			So(method, ShouldEqual, http.MethodPost)
+			So(path, ShouldEqual, "/prod/@connections/L0SM9cOFvHcCIhw=")
+			So(body, ShouldEqual, `{"message":"hello"}`)
+		})
+	})
+
+	Convey("GetConnection", t, func() {
+		Convey("Should return connection information", func() {
+			conn, err := client.GetConnection(context.Background(), "L0SM9cOFvHcCIhw=")
+			So(err, ShouldBeNil)
+			So(conn.Identity.SourceIP, ShouldEqual, "127.0.0.1")
+			So(conn.ConnectedAt.IsZero(), ShouldBeFalse)
+		})
+	})
+
+	Convey("DeleteConnection", t, func() {
+		Convey("Should return ErrWebSocketConnectionGone when the client already disconnected", func() {
+			err := client.DeleteConnection(context.Background(), "L0SM9cOFvHcCIhw=")
+			So(method, ShouldEqual, http.MethodDelete)
+			So(err, ShouldEqual, ErrWebSocketConnectionGone)
+		})
+	})
+}